@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
@@ -11,6 +12,9 @@ import (
 
 func New(cdnIn *cdn.CDN) *http.Server {
 	router := mux.NewRouter()
+	router.HandleFunc("/stats/object-cache", func(rw http.ResponseWriter, req *http.Request) {
+		handleObjectCacheStats(cdnIn, rw)
+	}).Methods(http.MethodGet)
 	if cdnIn.Config.Listen.Api == "" {
 		log.Fatalf("[Http] Invalid Listening Address")
 	}
@@ -26,6 +30,25 @@ func New(cdnIn *cdn.CDN) *http.Server {
 	return s
 }
 
+type objectCacheStats struct {
+	Enabled bool   `json:"enabled"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Size    int64  `json:"size"`
+	Entries int    `json:"entries"`
+}
+
+func handleObjectCacheStats(cdnIn *cdn.CDN, rw http.ResponseWriter) {
+	var theStats objectCacheStats
+	if cdnIn.ObjectCache != nil {
+		theStats.Enabled = true
+		theStats.Hits, theStats.Misses, theStats.Size, theStats.Entries = cdnIn.ObjectCache.Stats()
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(rw).Encode(theStats)
+}
+
 func runBackgroundHttp(s *http.Server) {
 	err := s.ListenAndServe()
 	if err != nil {
@@ -0,0 +1,133 @@
+package cdn
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+type accessLimitStateEntry struct {
+	Zone                    string `yaml:"zone"`
+	limits.AccessLimitKey   `yaml:",inline"`
+	limits.AccessLimitState `yaml:",inline"`
+}
+
+var stateFileLocks sync.Map
+
+func getStateFileLock(stateFilePath string) *sync.Mutex {
+	if absPath, err := filepath.Abs(stateFilePath); err == nil {
+		stateFilePath = absPath
+	}
+	theLock, _ := stateFileLocks.LoadOrStore(stateFilePath, new(sync.Mutex))
+	return theLock.(*sync.Mutex)
+}
+
+func readAccessLimitStateFile(stateFilePath string) ([]accessLimitStateEntry, error) {
+	stateFile, err := os.Open(stateFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer stateFile.Close()
+	var states []accessLimitStateEntry
+	err = yaml.NewDecoder(stateFile).Decode(&states)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (zone *Zone) loadAccessLimitState() error {
+	stateLock := getStateFileLock(zone.Config.AccessLimit.StateFile)
+	stateLock.Lock()
+	states, err := readAccessLimitStateFile(zone.Config.AccessLimit.StateFile)
+	stateLock.Unlock()
+	if err != nil {
+		return err
+	}
+	theScope := zone.Config.AccessLimit.GetScope()
+	zone.mutAccess.Lock()
+	defer zone.mutAccess.Unlock()
+	for _, v := range states {
+		if v.Zone != zone.Config.Name || v.Scope != theScope {
+			continue
+		}
+		zone.addAccessLimit(v.AccessLimitKey, limits.NewAccessLimitFromState(zone.getObjectPolicy(v.Path).ApplyToAccessLimit(zone.Config.AccessLimit), v.AccessLimitState))
+	}
+	return nil
+}
+
+func (zone *Zone) saveAccessLimitState() error {
+	if zone.Config.AccessLimit.StateFile == "" {
+		return nil
+	}
+	states := make([]accessLimitStateEntry, 0)
+	zone.mutAccess.RLock()
+	for k, v := range zone.AccessLimits {
+		if v.Active() {
+			states = append(states, accessLimitStateEntry{Zone: zone.Config.Name, AccessLimitKey: k, AccessLimitState: v.GetState()})
+		}
+	}
+	zone.mutAccess.RUnlock()
+
+	stateLock := getStateFileLock(zone.Config.AccessLimit.StateFile)
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	oldStates, err := readAccessLimitStateFile(zone.Config.AccessLimit.StateFile)
+	if err != nil {
+		return err
+	}
+	for _, v := range oldStates {
+		if v.Zone != zone.Config.Name {
+			states = append(states, v)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Zone != states[j].Zone {
+			return states[i].Zone < states[j].Zone
+		}
+		if states[i].Path != states[j].Path {
+			return states[i].Path < states[j].Path
+		}
+		if states[i].Scope != states[j].Scope {
+			return states[i].Scope < states[j].Scope
+		}
+		return states[i].Client < states[j].Client
+	})
+
+	tempFile, err := os.CreateTemp(filepath.Dir(zone.Config.AccessLimit.StateFile), ".snowedin-state-")
+	if err != nil {
+		return err
+	}
+	err = yaml.NewEncoder(tempFile).Encode(states)
+	if err == nil {
+		err = tempFile.Close()
+	} else {
+		_ = tempFile.Close()
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), zone.Config.AccessLimit.StateFile)
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+	}
+	return err
+}
+
+func (zone *Zone) runAccessLimitStateFlusher() {
+	for range time.Tick(zone.Config.AccessLimit.GetStateFlushInterval()) {
+		err := zone.saveAccessLimitState()
+		if err != nil {
+			utils.LogPrintln(1, "Access Limit State Save Error: "+err.Error())
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cdn
+
+import (
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStateTestZone(name string, stateFile string) *Zone {
+	return &Zone{
+		Config: conf.ZoneYaml{
+			Name:        name,
+			AccessLimit: conf.AccessLimitYaml{AccessLimit: 2, ExpireTime: time.Hour, StateFile: stateFile},
+		},
+		mutAccess:        new(sync.RWMutex),
+		AccessLimits:     make(map[limits.AccessLimitKey]*limits.AccessLimit),
+		accessLimitPaths: make(map[string]map[limits.AccessLimitKey]struct{}),
+	}
+}
+
+func TestAccessLimitStateRoundTripWithSharedFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.yml")
+	zoneA := newStateTestZone("a", stateFile)
+	zoneB := newStateTestZone("b", stateFile)
+
+	expiredKey := limits.AccessLimitKey{Path: "expired.txt", Scope: "object"}
+	expired := limits.NewAccessLimit(zoneA.Config.AccessLimit)
+	expired.ExpireTime = time.Now().Add(-time.Minute)
+	zoneA.AccessLimits[expiredKey] = expired
+
+	usedKey := limits.AccessLimitKey{Path: "used.txt", Scope: "object"}
+	used := limits.NewAccessLimit(zoneB.Config.AccessLimit)
+	used.AccessLimitReached()
+	used.AccessLimitReached()
+	zoneB.AccessLimits[usedKey] = used
+
+	if err := zoneA.saveAccessLimitState(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zoneB.saveAccessLimitState(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zoneA.saveAccessLimitState(); err != nil {
+		t.Fatal(err)
+	}
+
+	loadedA := newStateTestZone("a", stateFile)
+	loadedB := newStateTestZone("b", stateFile)
+	if err := loadedA.loadAccessLimitState(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadedB.loadAccessLimitState(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loadedA.AccessLimits) != 1 || loadedA.AccessLimits[expiredKey] == nil || !loadedA.AccessLimits[expiredKey].Expired() {
+		t.Fatalf("expected zone a to restore its expired limit, got %v", loadedA.AccessLimits)
+	}
+	if len(loadedB.AccessLimits) != 1 || loadedB.AccessLimits[usedKey] == nil || !loadedB.AccessLimits[usedKey].AccessLimitReached() {
+		t.Fatalf("expected zone b to restore its used up limit, got %v", loadedB.AccessLimits)
+	}
+}
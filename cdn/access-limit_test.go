@@ -0,0 +1,36 @@
+package cdn
+
+import (
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+	"time"
+)
+
+func TestClearAccessLimits(t *testing.T) {
+	zone := newStateTestZone("z", "")
+	keys := []limits.AccessLimitKey{
+		{Path: "a.txt", Scope: "clientIP", Client: "203.0.113.1"},
+		{Path: "a.txt", Scope: "clientIP", Client: "203.0.113.2"},
+		{Path: "b.txt", Scope: "clientIP", Client: "203.0.113.1"},
+	}
+	for _, k := range keys {
+		zone.checkAccessLimits(k, conf.AccessLimitYaml{}).Release()
+	}
+
+	zone.clearAccessLimits("a.txt")
+	if len(zone.AccessLimits) != 1 || zone.AccessLimits[keys[2]] == nil {
+		t.Fatalf("expected only the other path to remain, got %v", zone.AccessLimits)
+	}
+	if len(zone.accessLimitPaths) != 1 || len(zone.accessLimitPaths["b.txt"]) != 1 {
+		t.Fatalf("expected the path index to follow the limits, got %v", zone.accessLimitPaths)
+	}
+
+	zone.AccessLimits[keys[2]].LastUsed = time.Now().Add(-time.Hour)
+	zone.mutAccess.Lock()
+	limits.CleanStateMapFunc(zone.AccessLimits, time.Minute, 0, zone.removeAccessLimitPath)
+	zone.mutAccess.Unlock()
+	if len(zone.AccessLimits) != 0 || len(zone.accessLimitPaths) != 0 {
+		t.Fatalf("expected the janitor to clean the path index, got %v", zone.accessLimitPaths)
+	}
+}
@@ -0,0 +1,90 @@
+package cdn
+
+import (
+	"crypto/subtle"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strings"
+)
+
+func isMutatingMethod(method string) bool {
+	return method == http.MethodDelete || method == http.MethodPut
+}
+
+func processAuthorization(rw http.ResponseWriter, req *http.Request, clientIP string, config conf.AuthorizationYaml) bool {
+	if !config.YamlValid() {
+		return true
+	}
+	if !config.AddressContained(clientIP) {
+		utils.SetNeverCacheHeader(rw.Header())
+		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusForbidden, "Forbidden Address")
+		return false
+	}
+	if !config.CredentialsRequired() || credentialsValid(req, config) {
+		return true
+	}
+	utils.SetNeverCacheHeader(rw.Header())
+	if len(config.BasicUsers) != 0 {
+		rw.Header().Add("WWW-Authenticate", "Basic realm=\""+config.GetRealm()+"\", charset=\"UTF-8\"")
+	}
+	if len(config.BearerTokens) != 0 {
+		rw.Header().Add("WWW-Authenticate", "Bearer realm=\""+config.GetRealm()+"\"")
+	}
+	writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusUnauthorized, "Unauthorized")
+	return false
+}
+
+func credentialsValid(req *http.Request, config conf.AuthorizationYaml) bool {
+	authHeader := req.Header.Get("Authorization")
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		theToken := []byte(strings.TrimSpace(authHeader[7:]))
+		valid := false
+		for _, s := range config.BearerTokens {
+			if subtle.ConstantTimeCompare([]byte(s), theToken) == 1 {
+				valid = true
+			}
+		}
+		return valid
+	}
+	if username, password, ok := req.BasicAuth(); ok {
+		if theHash, exists := config.BasicUsers[username]; exists {
+			return bcrypt.CompareHashAndPassword([]byte(theHash), []byte(password)) == nil
+		}
+	}
+	return false
+}
+
+func getRequestToken(req *http.Request) string {
+	authHeader := req.Header.Get("Authorization")
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		return strings.TrimSpace(authHeader[7:])
+	}
+	return ""
+}
+
+func downloadTokenValid(theToken string, downloadTokens []string) bool {
+	if theToken == "" {
+		return false
+	}
+	valid := false
+	for _, s := range downloadTokens {
+		if subtle.ConstantTimeCompare([]byte(s), []byte(theToken)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func processSignedUrl(rw http.ResponseWriter, req *http.Request, clientIP string, lookupPath string, config conf.SignedUrlsYaml) bool {
+	if !config.BindClientIP {
+		clientIP = ""
+	}
+	if utils.UrlSignatureValid(config.Secret, lookupPath, req.URL.Query(), clientIP) {
+		return true
+	}
+	utils.SetNeverCacheHeader(rw.Header())
+	writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusForbidden, "Invalid Signature")
+	return false
+}
@@ -0,0 +1,172 @@
+package cdn
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessAuthorization(t *testing.T) {
+	theHash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := conf.AuthorizationYaml{
+		BearerTokens: []string{"token-one", "token-two"},
+		BasicUsers:   map[string]string{"admin": string(theHash)},
+	}
+	addressOnly := conf.AuthorizationYaml{
+		RemoteAddresses: []string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.5"},
+	}
+	both := conf.AuthorizationYaml{
+		BearerTokens:    []string{"token-one"},
+		RemoteAddresses: []string{"10.0.0.0/8"},
+	}
+
+	tests := []struct {
+		name     string
+		config   conf.AuthorizationYaml
+		clientIP string
+		header   string
+		basic    []string
+		status   int
+	}{
+		{"disabled", conf.AuthorizationYaml{}, "203.0.113.1", "", nil, 0},
+		{"missing credentials", credentials, "203.0.113.1", "", nil, http.StatusUnauthorized},
+		{"bearer token", credentials, "203.0.113.1", "Bearer token-one", nil, 0},
+		{"second bearer token", credentials, "203.0.113.1", "Bearer token-two", nil, 0},
+		{"bearer scheme is case insensitive", credentials, "203.0.113.1", "bearer token-one", nil, 0},
+		{"bearer token with padding", credentials, "203.0.113.1", "Bearer  token-one ", nil, 0},
+		{"unknown bearer token", credentials, "203.0.113.1", "Bearer token-three", nil, http.StatusUnauthorized},
+		{"bearer token prefix", credentials, "203.0.113.1", "Bearer token-on", nil, http.StatusUnauthorized},
+		{"empty bearer token", credentials, "203.0.113.1", "Bearer ", nil, http.StatusUnauthorized},
+		{"bearer token as basic password", credentials, "203.0.113.1", "", []string{"admin", "token-one"}, http.StatusUnauthorized},
+		{"basic user", credentials, "203.0.113.1", "", []string{"admin", "hunter2"}, 0},
+		{"basic wrong password", credentials, "203.0.113.1", "", []string{"admin", "hunter3"}, http.StatusUnauthorized},
+		{"basic unknown user", credentials, "203.0.113.1", "", []string{"root", "hunter2"}, http.StatusUnauthorized},
+		{"basic hash as password", credentials, "203.0.113.1", "", []string{"admin", string(theHash)}, http.StatusUnauthorized},
+		{"unsupported scheme", credentials, "203.0.113.1", "Digest username=\"admin\"", nil, http.StatusUnauthorized},
+		{"address in cidr", addressOnly, "10.1.2.3", "", nil, 0},
+		{"address outside cidr", addressOnly, "11.1.2.3", "", nil, http.StatusForbidden},
+		{"exact address", addressOnly, "192.168.1.5", "", nil, 0},
+		{"neighbouring address", addressOnly, "192.168.1.6", "", nil, http.StatusForbidden},
+		{"ipv6 address in cidr", addressOnly, "2001:db8::1", "", nil, 0},
+		{"ipv6 address outside cidr", addressOnly, "2001:db9::1", "", nil, http.StatusForbidden},
+		{"ipv4 mapped address in cidr", addressOnly, "::ffff:10.0.0.1", "", nil, 0},
+		{"invalid address", addressOnly, "not-an-ip", "", nil, http.StatusForbidden},
+		{"address and token", both, "10.0.0.1", "Bearer token-one", nil, 0},
+		{"address without token", both, "10.0.0.1", "", nil, http.StatusUnauthorized},
+		{"token from outside address", both, "203.0.113.1", "Bearer token-one", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/zone/object", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.basic != nil {
+				req.SetBasicAuth(tt.basic[0], tt.basic[1])
+			}
+			rec := httptest.NewRecorder()
+			ok := processAuthorization(rec, req, tt.clientIP, tt.config)
+			if tt.status == 0 {
+				if !ok {
+					t.Fatalf("expected authorization to pass, got %d", rec.Code)
+				}
+				return
+			}
+			if ok || rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d (passed: %v)", tt.status, rec.Code, ok)
+			}
+			if tt.status == http.StatusUnauthorized && len(rec.Header().Values("WWW-Authenticate")) == 0 {
+				t.Fatal("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestProcessAuthorizationChallenges(t *testing.T) {
+	config := conf.AuthorizationYaml{
+		BearerTokens: []string{"token-one"},
+		BasicUsers:   map[string]string{"admin": "$2a$04$invalid"},
+		Realm:        "uploads",
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/zone/object", nil)
+	rec := httptest.NewRecorder()
+	processAuthorization(rec, req, "203.0.113.1", config)
+	challenges := rec.Header().Values("WWW-Authenticate")
+	if len(challenges) != 2 || challenges[0] != "Basic realm=\"uploads\", charset=\"UTF-8\"" || challenges[1] != "Bearer realm=\"uploads\"" {
+		t.Fatalf("unexpected challenges: %q", challenges)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc == "" {
+		t.Fatal("expected the unauthorized response to be uncacheable")
+	}
+}
+
+func TestSignedUrlsForMutatingMethods(t *testing.T) {
+	const secret = "signing-secret"
+	newZone := func(authorization conf.AuthorizationYaml) *Zone {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		zone := NewZone(conf.ZoneYaml{
+			Name:            "z",
+			Backend:         "filesystem",
+			BackendSettings: map[string]string{"directoryPath": dir},
+			SignedUrls:      conf.SignedUrlsYaml{Secret: secret},
+			Authorization:   authorization,
+		}, conf.ListenYaml{}, limits.NewGlobalLimits(conf.GlobalLimitsYaml{}), limits.NewBandwidthBuckets(), nil, 0)
+		if zone == nil {
+			t.Fatal("failed to create the zone")
+		}
+		return zone
+	}
+	validQuery := utils.SignUrlQuery(secret, "a.txt", time.Now().Add(time.Minute), "")
+	invalidQuery := utils.SignUrlQuery("other-secret", "a.txt", time.Now().Add(time.Minute), "")
+
+	tests := []struct {
+		name          string
+		authorization conf.AuthorizationYaml
+		method        string
+		query         string
+		header        string
+		rejected      bool
+	}{
+		{"put without signature", conf.AuthorizationYaml{}, http.MethodPut, "", "", true},
+		{"put with invalid signature", conf.AuthorizationYaml{}, http.MethodPut, invalidQuery, "", true},
+		{"put with valid signature", conf.AuthorizationYaml{}, http.MethodPut, validQuery, "", false},
+		{"delete without signature", conf.AuthorizationYaml{}, http.MethodDelete, "", "", true},
+		{"delete with invalid signature", conf.AuthorizationYaml{}, http.MethodDelete, invalidQuery, "", true},
+		{"delete with valid signature", conf.AuthorizationYaml{}, http.MethodDelete, validQuery, "", false},
+		{"authorized delete without signature", conf.AuthorizationYaml{BearerTokens: []string{"token"}}, http.MethodDelete, "", "Bearer token", false},
+		{"get without signature", conf.AuthorizationYaml{BearerTokens: []string{"token"}}, http.MethodGet, "", "Bearer token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := newZone(tt.authorization)
+			target := "/z/a.txt"
+			if tt.query != "" {
+				target += "?" + tt.query
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader("world"))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			zone.ZoneHandleRequest(rec, req)
+			rejected := rec.Code == http.StatusForbidden && strings.HasPrefix(rec.Body.String(), "Invalid Signature")
+			if rejected != tt.rejected {
+				t.Fatalf("expected rejected %v, got %d %q", tt.rejected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,25 +1,74 @@
 package cdn
 
 import (
-	"io"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/archive"
 	"snow.mrmelon54.xyz/snowedin/cdn/backends/filesystem"
-	"time"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/httpcache"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/httporigin"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/memory"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/s3"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/union"
+	"strconv"
+	"strings"
 )
 
-type Backend interface {
-	WriteData(path string, rw io.Writer) (err error)
-	WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error)
-	MimeType(path string) (mimetype string)
-	ETag(path string) (eTag string)
-	Stats(path string) (size int64, modified time.Time, err error)
-	Purge(path string) (err error)
-	Exists(path string) (exists bool, listable bool)
-	List(path string) (entries []string, err error)
-}
+type Backend = backends.Backend
+
+type WritableBackend = backends.WritableBackend
+
+type PolicyBackend = backends.PolicyBackend
+
+type PrecompressedBackend = backends.PrecompressedBackend
 
 func NewBackendFromName(name string, confMap map[string]string) Backend {
-	if name == "filesystem" {
+	switch name {
+	case "filesystem":
 		return filesystem.NewBackendFilesystem(confMap)
+	case "http":
+		if theBackend := httporigin.NewBackendHttp(confMap); theBackend != nil {
+			return theBackend
+		}
+	case "httpcache":
+		if theBackend := httpcache.NewBackendHttpCache(confMap); theBackend != nil {
+			return theBackend
+		}
+	case "s3":
+		if theBackend := s3.NewBackendS3(confMap); theBackend != nil {
+			return theBackend
+		}
+	case "archive":
+		if theBackend := archive.NewBackendArchive(confMap); theBackend != nil {
+			return theBackend
+		}
+	case "memory":
+		return memory.NewBackendMemory(confMap)
+	case "union":
+		if theBackend := union.NewBackendUnion(getUnionChildren(confMap)); theBackend != nil {
+			return theBackend
+		}
 	}
 	return nil
 }
+
+func getUnionChildren(confMap map[string]string) []backends.Backend {
+	if confMap["children"] == "" {
+		return nil
+	}
+	names := strings.Split(confMap["children"], ",")
+	children := make([]backends.Backend, len(names))
+	for i, n := range names {
+		prefix := strconv.Itoa(i) + "."
+		childConfMap := make(map[string]string)
+		for k, v := range confMap {
+			if strings.HasPrefix(k, prefix) {
+				childConfMap[strings.TrimPrefix(k, prefix)] = v
+			}
+		}
+		children[i] = NewBackendFromName(strings.TrimSpace(n), childConfMap)
+		if children[i] == nil {
+			return nil
+		}
+	}
+	return children
+}
@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"strconv"
+	"time"
+)
+
+func NewArchiveDirectoryEntry(modifiedTime time.Time) *ArchiveEntry {
+	return &ArchiveEntry{
+		size:       -1,
+		modifyTime: modifiedTime,
+		dataOffset: -1,
+		directory:  true,
+	}
+}
+
+type ArchiveEntry struct {
+	archive    *ArchiveFile
+	name       string
+	size       int64
+	modifyTime time.Time
+	eTag       string
+	zipFile    *zip.File
+	dataOffset int64
+	directory  bool
+}
+
+func getZipETag(zf *zip.FileHeader) string {
+	return "\"" + strconv.FormatUint(uint64(zf.CRC32), 16) + "-" + strconv.FormatUint(zf.UncompressedSize64, 16) + "\""
+}
+
+func getTarETag(header *tar.Header, dataOffset int64) string {
+	return "\"" + strconv.FormatInt(header.ModTime.Unix(), 16) + "-" + strconv.FormatInt(header.Size, 16) + "-" + strconv.FormatInt(dataOffset, 16) + "\""
+}
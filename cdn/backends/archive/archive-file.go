@@ -0,0 +1,234 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"os"
+	pth "path"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	kindZip = iota
+	kindTar
+	kindTarGzip
+)
+
+func getArchiveKind(name string) (kind int, mountName string, ok bool) {
+	lname := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lname, ".zip"):
+		return kindZip, name[:len(name)-len(".zip")], true
+	case strings.HasSuffix(lname, ".tar"):
+		return kindTar, name[:len(name)-len(".tar")], true
+	case strings.HasSuffix(lname, ".tar.gz"):
+		return kindTarGzip, name[:len(name)-len(".tar.gz")], true
+	case strings.HasSuffix(lname, ".tgz"):
+		return kindTarGzip, name[:len(name)-len(".tgz")], true
+	}
+	return 0, "", false
+}
+
+func OpenArchiveFile(filePath string, kind int) (*ArchiveFile, error) {
+	fStats, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	aFile := &ArchiveFile{
+		filePath:   filePath,
+		kind:       kind,
+		size:       fStats.Size(),
+		modifyTime: fStats.ModTime(),
+		mu:         &sync.Mutex{},
+	}
+	if kind == kindTarGzip {
+		aFile.file, err = extractTarGzip(filePath)
+	} else {
+		aFile.file, err = os.Open(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if kind == kindZip {
+		aFile.zipReader, err = zip.NewReader(aFile.file, aFile.size)
+		if err != nil {
+			utils.MustClose(aFile.file)
+			return nil, err
+		}
+	}
+	return aFile, nil
+}
+
+func extractTarGzip(filePath string) (*os.File, error) {
+	theFile, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.MustClose(theFile)
+	gzReader, err := gzip.NewReader(theFile)
+	if err != nil {
+		return nil, err
+	}
+	tarFile, err := os.CreateTemp("", "snowedin-archive-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(tarFile, gzReader)
+	if err == nil {
+		err = gzReader.Close()
+	}
+	if err != nil {
+		utils.MustClose(tarFile)
+		_ = os.Remove(tarFile.Name())
+		return nil, err
+	}
+	return tarFile, nil
+}
+
+type ArchiveFile struct {
+	filePath   string
+	kind       int
+	size       int64
+	modifyTime time.Time
+	file       *os.File
+	zipReader  *zip.Reader
+	mu         *sync.Mutex
+	users      int
+	retired    bool
+}
+
+func (a *ArchiveFile) unchanged(fStats os.FileInfo) bool {
+	return a.size == fStats.Size() && a.modifyTime.Equal(fStats.ModTime())
+}
+
+func (a *ArchiveFile) acquire() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.retired {
+		return false
+	}
+	a.users++
+	return true
+}
+
+func (a *ArchiveFile) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users--
+	if a.users == 0 && a.retired {
+		a.close()
+	}
+}
+
+func (a *ArchiveFile) retire() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.retired = true
+	if a.users == 0 {
+		a.close()
+	}
+}
+
+func (a *ArchiveFile) close() {
+	if a.file == nil {
+		return
+	}
+	utils.MustClose(a.file)
+	if a.kind == kindTarGzip {
+		_ = os.Remove(a.file.Name())
+	}
+	a.file = nil
+}
+
+func (a *ArchiveFile) readEntries(mountPath string, addEntry func(path string, entry *ArchiveEntry)) error {
+	switch a.kind {
+	case kindZip:
+		for _, zf := range a.zipReader.File {
+			name := cleanPath(pth.Join(mountPath, zf.Name))
+			if zf.FileInfo().IsDir() {
+				addEntry(name, NewArchiveDirectoryEntry(zf.Modified))
+				continue
+			}
+			var dataOffset int64 = -1
+			if zf.Method == zip.Store {
+				if offset, err := zf.DataOffset(); err == nil {
+					dataOffset = offset
+				}
+			}
+			addEntry(name, &ArchiveEntry{
+				archive:    a,
+				name:       zf.Name,
+				size:       int64(zf.UncompressedSize64),
+				modifyTime: zf.Modified,
+				eTag:       getZipETag(&zf.FileHeader),
+				zipFile:    zf,
+				dataOffset: dataOffset,
+			})
+		}
+		return nil
+	case kindTar, kindTarGzip:
+		_, err := a.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		return a.readTarEntries(a.file, mountPath, addEntry)
+	}
+	return errors.New("unknown archive kind")
+}
+
+func (a *ArchiveFile) readTarEntries(reader io.Reader, mountPath string, addEntry func(path string, entry *ArchiveEntry)) error {
+	tReader := tar.NewReader(reader)
+	for {
+		header, err := tReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := cleanPath(pth.Join(mountPath, header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			addEntry(name, NewArchiveDirectoryEntry(header.ModTime))
+		case tar.TypeReg:
+			var dataOffset int64 = -1
+			if seeker, ok := reader.(io.Seeker); ok {
+				if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+					dataOffset = offset
+				}
+			}
+			addEntry(name, &ArchiveEntry{
+				archive:    a,
+				name:       header.Name,
+				size:       header.Size,
+				modifyTime: header.ModTime,
+				eTag:       getTarETag(header, dataOffset),
+				dataOffset: dataOffset,
+			})
+		}
+	}
+}
+
+func (a *ArchiveFile) openEntry(entry *ArchiveEntry) (io.ReadCloser, error) {
+	if entry.zipFile != nil {
+		return entry.zipFile.Open()
+	}
+	tReader := tar.NewReader(io.NewSectionReader(a.file, 0, math.MaxInt64))
+	for {
+		header, err := tReader.Next()
+		if err == io.EOF {
+			return nil, errors.New("archive entry missing")
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Name == entry.name && header.Typeflag == tar.TypeReg {
+			return io.NopCloser(tReader), nil
+		}
+	}
+}
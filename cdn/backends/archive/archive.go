@@ -0,0 +1,309 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"os"
+	pth "path"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func NewBackendArchive(confMap map[string]string) *BackendArchive {
+	if confMap["archivePath"] == "" {
+		return nil
+	}
+	fStats, err := os.Stat(confMap["archivePath"])
+	if err != nil {
+		return nil
+	}
+	if !fStats.IsDir() {
+		if _, _, ok := getArchiveKind(fStats.Name()); !ok {
+			return nil
+		}
+	}
+	var wmod = false
+	if confMap["watchModified"] != "" {
+		wmod, _ = strconv.ParseBool(confMap["watchModified"])
+	}
+	var mtbe = true
+	if confMap["mimeTypeByExtension"] != "" {
+		lmtbe, err := strconv.ParseBool(confMap["mimeTypeByExtension"])
+		if err == nil {
+			mtbe = lmtbe
+		}
+	}
+	var dirl = false
+	if confMap["listDirectories"] != "" {
+		dirl, _ = strconv.ParseBool(confMap["listDirectories"])
+	}
+	toReturn := &BackendArchive{
+		archivePath:         confMap["archivePath"],
+		archiveDirectory:    fStats.IsDir(),
+		watchModified:       wmod,
+		mimeTypeByExtension: mtbe,
+		directoryListing:    dirl,
+		archiveFiles:        make(map[string]*ArchiveFile),
+		syncer:              &sync.RWMutex{},
+	}
+	toReturn.reload(false)
+	return toReturn
+}
+
+type BackendArchive struct {
+	archivePath         string
+	archiveDirectory    bool
+	watchModified       bool
+	mimeTypeByExtension bool
+	directoryListing    bool
+	archiveFiles        map[string]*ArchiveFile
+	entries             map[string]*ArchiveEntry
+	directories         map[string][]string
+	syncer              *sync.RWMutex
+}
+
+func (b *BackendArchive) ETag(path string) (eTag string) {
+	if entry := b.getEntry(path); entry != nil {
+		return entry.eTag
+	}
+	return ""
+}
+
+func (b *BackendArchive) MimeType(path string) (mimetype string) {
+	pext := pth.Ext(path)
+	if b.mimeTypeByExtension && pext != "" {
+		return mime.TypeByExtension(pext)
+	} else {
+		return ""
+	}
+}
+
+func (b *BackendArchive) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	entry := b.getEntry(path)
+	if entry == nil {
+		return errors.New("object does not exist")
+	}
+	if entry.directory {
+		return errors.New("object not writeable")
+	}
+	if !entry.archive.acquire() {
+		return errors.New("archive reloaded")
+	}
+	defer entry.archive.release()
+	if entry.dataOffset >= 0 {
+		_, err = io.Copy(rw, io.NewSectionReader(entry.archive.file, entry.dataOffset+index, length))
+		return err
+	}
+	theReader, err := entry.archive.openEntry(entry)
+	if err != nil {
+		return err
+	}
+	defer utils.MustClose(theReader)
+	_, err = io.CopyN(io.Discard, theReader, index)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(rw, io.LimitReader(theReader, length))
+	return err
+}
+
+func (b *BackendArchive) WriteData(path string, rw io.Writer) (err error) {
+	entry := b.getEntry(path)
+	if entry == nil {
+		return errors.New("object does not exist")
+	}
+	return b.WriteDataRange(path, rw, 0, entry.size)
+}
+
+func (b *BackendArchive) Stats(path string) (size int64, modified time.Time, err error) {
+	entry := b.getEntry(path)
+	if entry == nil {
+		return 0, time.Time{}, errors.New("object does not exist")
+	}
+	return entry.size, entry.modifyTime.UTC(), nil
+}
+
+func (b *BackendArchive) Purge(path string) (err error) {
+	if b.archivesModified() {
+		b.reload(true)
+	}
+	return nil
+}
+
+func (b *BackendArchive) Exists(path string) (exists bool, listable bool) {
+	if b.watchModified && b.archivesModified() {
+		b.reload(true)
+	}
+	entry := b.getEntry(path)
+	if entry == nil {
+		return false, false
+	}
+	if entry.directory {
+		return b.directoryListing, true
+	}
+	return true, false
+}
+
+func (b *BackendArchive) List(path string) (entries []string, err error) {
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	names, ok := b.directories[cleanPath(path)]
+	if !ok {
+		return nil, errors.New("directory does not exist")
+	}
+	contents := make([]string, len(names))
+	copy(contents, names)
+	return contents, nil
+}
+
+func (b *BackendArchive) getEntry(path string) *ArchiveEntry {
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	return b.entries[cleanPath(path)]
+}
+
+func (b *BackendArchive) findArchives() map[string]int {
+	found := make(map[string]int)
+	if b.archiveDirectory {
+		if dir, err := os.ReadDir(b.archivePath); err == nil {
+			for _, d := range dir {
+				if kind, _, ok := getArchiveKind(d.Name()); ok && !d.IsDir() {
+					found[filepath.Join(b.archivePath, d.Name())] = kind
+				}
+			}
+		}
+	} else {
+		kind, _, _ := getArchiveKind(filepath.Base(b.archivePath))
+		found[b.archivePath] = kind
+	}
+	return found
+}
+
+func (b *BackendArchive) archivesModified() bool {
+	found := b.findArchives()
+	stats := make(map[string]os.FileInfo, len(found))
+	for fPath := range found {
+		fStats, err := os.Stat(fPath)
+		if err != nil {
+			return true
+		}
+		stats[fPath] = fStats
+	}
+
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	if b.entries == nil || len(found) != len(b.archiveFiles) {
+		return true
+	}
+	for fPath, fStats := range stats {
+		if aFile := b.archiveFiles[fPath]; aFile == nil || !aFile.unchanged(fStats) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BackendArchive) reload(onlyIfModified bool) {
+	b.syncer.Lock()
+	defer b.syncer.Unlock()
+
+	found := b.findArchives()
+	modified := !onlyIfModified || b.entries == nil || len(found) != len(b.archiveFiles)
+	newArchiveFiles := make(map[string]*ArchiveFile)
+	for fPath, kind := range found {
+		fStats, err := os.Stat(fPath)
+		if err != nil {
+			modified = true
+			continue
+		}
+		if aFile := b.archiveFiles[fPath]; aFile != nil && aFile.unchanged(fStats) && onlyIfModified {
+			newArchiveFiles[fPath] = aFile
+			continue
+		}
+		modified = true
+		if aFile, err := OpenArchiveFile(fPath, kind); err == nil {
+			newArchiveFiles[fPath] = aFile
+		}
+	}
+	if !modified {
+		return
+	}
+	for fPath, aFile := range b.archiveFiles {
+		if newArchiveFiles[fPath] != aFile {
+			aFile.retire()
+		}
+	}
+	b.archiveFiles = newArchiveFiles
+
+	b.entries = make(map[string]*ArchiveEntry)
+	b.directories = make(map[string][]string)
+	b.addDirectory("")
+	for fPath, aFile := range b.archiveFiles {
+		mountPath := ""
+		if b.archiveDirectory {
+			_, mountPath, _ = getArchiveKind(filepath.Base(fPath))
+			b.addEntry(mountPath, NewArchiveDirectoryEntry(aFile.modifyTime))
+		}
+		_ = aFile.readEntries(mountPath, b.addEntry)
+	}
+	for _, names := range b.directories {
+		sort.Strings(names)
+	}
+}
+
+func (b *BackendArchive) addEntry(path string, entry *ArchiveEntry) {
+	if path == "" {
+		return
+	}
+	if entry.directory {
+		b.addDirectory(path)
+		if !entry.modifyTime.IsZero() {
+			b.entries[path].modifyTime = entry.modifyTime
+		}
+		return
+	}
+	parent := parentPath(path)
+	b.addDirectory(parent)
+	b.directories[parent] = appendName(b.directories[parent], pth.Base(path))
+	b.entries[path] = entry
+}
+
+func (b *BackendArchive) addDirectory(path string) {
+	if _, ok := b.directories[path]; ok {
+		return
+	}
+	b.directories[path] = []string{}
+	b.entries[path] = NewArchiveDirectoryEntry(time.Time{})
+	if path != "" {
+		parent := parentPath(path)
+		b.addDirectory(parent)
+		b.directories[parent] = appendName(b.directories[parent], pth.Base(path))
+	}
+}
+
+func parentPath(path string) string {
+	parent := pth.Dir(path)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
+
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+func cleanPath(path string) string {
+	return strings.TrimPrefix(pth.Clean("/"+path), "/")
+}
@@ -0,0 +1,106 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTarGzip(t *testing.T, filePath string, files map[string]string) {
+	buf := new(bytes.Buffer)
+	gzWriter := gzip.NewWriter(buf)
+	tWriter := tar.NewWriter(gzWriter)
+	for name, content := range files {
+		if err := tWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveTarGzipEntries(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	archivePath := filepath.Join(t.TempDir(), "assets.tar.gz")
+	writeTarGzip(t, archivePath, map[string]string{"a.txt": "hello world", "dir/b.txt": "second"})
+
+	b := NewBackendArchive(map[string]string{"archivePath": archivePath})
+	if b == nil {
+		t.Fatal("failed to create the backend")
+	}
+	entry := b.getEntry("a.txt")
+	if entry == nil || entry.dataOffset < 0 {
+		t.Fatal("expected the tar.gz entry to be indexed with a data offset")
+	}
+
+	if eTag := b.ETag("a.txt"); eTag == "" || eTag == b.ETag("dir/b.txt") {
+		t.Fatalf("expected distinct ETags for tar entries, got %q", eTag)
+	}
+
+	aFile := entry.archive
+	_ = b.Purge("a.txt")
+	if b.archiveFiles[archivePath] != aFile || aFile.file == nil {
+		t.Fatal("expected purging an unchanged archive to keep it open")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := b.WriteData("dir/b.txt", buf); err != nil || buf.String() != "second" {
+		t.Fatalf("unexpected content %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err := b.WriteDataRange("a.txt", buf, 6, 5); err != nil || buf.String() != "world" {
+		t.Fatalf("unexpected range %q: %v", buf.String(), err)
+	}
+
+	b.reload(false)
+	if aFile.file != nil {
+		t.Fatal("expected the retired archive to be closed")
+	}
+}
+
+func TestArchiveWatchModified(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	archivePath := filepath.Join(t.TempDir(), "assets.tgz")
+	writeTarGzip(t, archivePath, map[string]string{"a.txt": "one"})
+
+	b := NewBackendArchive(map[string]string{"archivePath": archivePath, "watchModified": "true"})
+	if b == nil {
+		t.Fatal("failed to create the backend")
+	}
+	if b.archivesModified() {
+		t.Fatal("expected the loaded archive to be unchanged")
+	}
+	aFile := b.archiveFiles[archivePath]
+	if exists, _ := b.Exists("a.txt"); !exists || b.archiveFiles[archivePath] != aFile {
+		t.Fatal("expected the unchanged archive to be kept")
+	}
+
+	writeTarGzip(t, archivePath, map[string]string{"b.txt": "two"})
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(archivePath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if !b.archivesModified() {
+		t.Fatal("expected the rewritten archive to be modified")
+	}
+	if exists, _ := b.Exists("b.txt"); !exists {
+		t.Fatal("expected the rewritten archive to be reloaded")
+	}
+	if exists, _ := b.Exists("a.txt"); exists {
+		t.Fatal("expected the old entry to be removed")
+	}
+}
@@ -0,0 +1,33 @@
+package backends
+
+import (
+	"io"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"time"
+)
+
+type Backend interface {
+	WriteData(path string, rw io.Writer) (err error)
+	WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error)
+	MimeType(path string) (mimetype string)
+	ETag(path string) (eTag string)
+	Stats(path string) (size int64, modified time.Time, err error)
+	Purge(path string) (err error)
+	Exists(path string) (exists bool, listable bool)
+	List(path string) (entries []string, err error)
+}
+
+type WritableBackend interface {
+	Backend
+	Store(path string, data io.Reader, size int64, contentType string) (err error)
+}
+
+type PolicyBackend interface {
+	Backend
+	Policy(path string) (policy conf.ObjectPolicyYaml)
+}
+
+type PrecompressedBackend interface {
+	Backend
+	PrecompressedEncodings(path string) (encodings []string)
+}
@@ -0,0 +1,112 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	pth "path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const eTagSidecarSuffix = ".snowetag"
+
+type contentHash struct {
+	size       int64
+	modifyTime time.Time
+	eTag       string
+}
+
+func isETagSidecar(path string) bool {
+	return strings.HasSuffix(pth.Base(path), eTagSidecarSuffix)
+}
+
+func (b *BackendFilesystem) setContentETag(path string, fStats os.FileInfo) bool {
+	if ch := b.contentHashes[path]; ch != nil && ch.size == fStats.Size() && ch.modifyTime.Equal(fStats.ModTime()) {
+		b.eTags[path] = b.getContentETag(ch)
+		return true
+	}
+	if b.eTagSidecars {
+		if ch := readETagSidecar(pth.Join(b.directoryPath, path)); ch != nil && ch.size == fStats.Size() && ch.modifyTime.Equal(fStats.ModTime()) {
+			b.contentHashes[path] = ch
+			b.eTags[path] = b.getContentETag(ch)
+			return true
+		}
+	}
+	if !b.hashing[path] {
+		b.hashing[path] = true
+		go b.hashContent(path, fStats.Size(), fStats.ModTime())
+	}
+	return false
+}
+
+func (b *BackendFilesystem) getContentETag(ch *contentHash) string {
+	if b.weakETags {
+		return "W/" + ch.eTag
+	}
+	return ch.eTag
+}
+
+func (b *BackendFilesystem) hashContent(path string, size int64, modified time.Time) {
+	ch := calculateContentHash(pth.Join(b.directoryPath, path), size, modified)
+	b.syncer.Lock()
+	delete(b.hashing, path)
+	if ch != nil {
+		b.contentHashes[path] = ch
+		b.eTags[path] = b.getContentETag(ch)
+	}
+	b.syncer.Unlock()
+	if ch != nil && b.eTagSidecars {
+		writeETagSidecar(pth.Join(b.directoryPath, path), ch)
+	}
+}
+
+func calculateContentHash(filePath string, size int64, modified time.Time) *contentHash {
+	theFile, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer theFile.Close()
+	theHash := sha256.New()
+	if _, err = io.Copy(theHash, theFile); err != nil {
+		return nil
+	}
+	if fStats, err := theFile.Stat(); err != nil || fStats.Size() != size || !fStats.ModTime().Equal(modified) {
+		return nil
+	}
+	return &contentHash{
+		size:       size,
+		modifyTime: modified,
+		eTag:       "\"" + hex.EncodeToString(theHash.Sum(nil)) + "\"",
+	}
+}
+
+func readETagSidecar(filePath string) *contentHash {
+	data, err := os.ReadFile(filePath + eTagSidecarSuffix)
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return nil
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+	modified, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &contentHash{
+		size:       size,
+		modifyTime: time.Unix(0, modified),
+		eTag:       "\"" + fields[2] + "\"",
+	}
+}
+
+func writeETagSidecar(filePath string, ch *contentHash) {
+	_ = os.WriteFile(filePath+eTagSidecarSuffix, []byte(strconv.FormatInt(ch.size, 10)+" "+strconv.FormatInt(ch.modifyTime.UnixNano(), 10)+" "+strings.Trim(ch.eTag, "\"")+"\n"), 0644)
+}
@@ -11,10 +11,19 @@ import (
 	pth "path"
 	"snow.mrmelon54.xyz/snowedin/utils"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
+const uploadTempPrefix = ".snowedin-upload-"
+
+var precompressedExtensions = []struct {
+	encoding  string
+	extension string
+}{{"br", ".br"}, {"zstd", ".zst"}, {"gzip", ".gz"}}
+
 func NewBackendFilesystem(confMap map[string]string) *BackendFilesystem {
 	wdir, _ := os.Getwd()
 	directory := wdir
@@ -55,6 +64,14 @@ func NewBackendFilesystem(confMap map[string]string) *BackendFilesystem {
 	if confMap["directoryModifiedTimeCheck"] != "" {
 		dmtc, _ = strconv.ParseBool(confMap["directoryModifiedTimeCheck"])
 	}
+	var polf = false
+	if confMap["policyFiles"] != "" {
+		polf, _ = strconv.ParseBool(confMap["policyFiles"])
+	}
+	var prec = false
+	if confMap["precompressedFiles"] != "" {
+		prec, _ = strconv.ParseBool(confMap["precompressedFiles"])
+	}
 	var etagstore map[string]string = nil
 	if confMap["calculateETags"] != "" {
 		calcETags, _ := strconv.ParseBool(confMap["calculateETags"])
@@ -62,6 +79,25 @@ func NewBackendFilesystem(confMap map[string]string) *BackendFilesystem {
 			etagstore = make(map[string]string)
 		}
 	}
+	var cetg = confMap["eTagMode"] == "content"
+	if cetg && etagstore == nil {
+		etagstore = make(map[string]string)
+	}
+	var weak = false
+	if confMap["weakETags"] != "" {
+		weak, _ = strconv.ParseBool(confMap["weakETags"])
+	}
+	var upmode os.FileMode = 0644
+	if confMap["uploadFileMode"] != "" {
+		lupmode, err := strconv.ParseUint(confMap["uploadFileMode"], 8, 32)
+		if err == nil && lupmode <= 0777 {
+			upmode = os.FileMode(lupmode)
+		}
+	}
+	var etgs = false
+	if confMap["eTagSidecars"] != "" {
+		etgs, _ = strconv.ParseBool(confMap["eTagSidecars"])
+	}
 	return &BackendFilesystem{
 		directoryPath:              directory,
 		cachedHeaderBytes:          chb,
@@ -74,6 +110,16 @@ func NewBackendFilesystem(confMap map[string]string) *BackendFilesystem {
 		fileObjects:                make(map[string]*FileObject),
 		eTags:                      etagstore,
 		syncer:                     &sync.Mutex{},
+		policyFiles:                polf,
+		precompressedFiles:         prec,
+		contentETags:               cetg,
+		eTagSidecars:               etgs,
+		weakETags:                  weak,
+		uploadFileMode:             upmode,
+		contentHashes:              make(map[string]*contentHash),
+		hashing:                    make(map[string]bool),
+		policies:                   make(map[string]*policyFile),
+		policySyncer:               &sync.Mutex{},
 	}
 }
 
@@ -89,6 +135,16 @@ type BackendFilesystem struct {
 	fileObjects                map[string]*FileObject
 	eTags                      map[string]string
 	syncer                     *sync.Mutex
+	policyFiles                bool
+	precompressedFiles         bool
+	contentETags               bool
+	eTagSidecars               bool
+	weakETags                  bool
+	uploadFileMode             os.FileMode
+	contentHashes              map[string]*contentHash
+	hashing                    map[string]bool
+	policies                   map[string]*policyFile
+	policySyncer               *sync.Mutex
 }
 
 func (b *BackendFilesystem) ETag(path string) (eTag string) {
@@ -200,7 +256,6 @@ func (b *BackendFilesystem) Stats(path string) (size int64, modified time.Time,
 }
 
 func (b *BackendFilesystem) setETag(path string, tagValue string, replaceExisting bool) {
-	b.syncer.Lock()
 	if replaceExisting || b.eTags[path] == "" {
 		theHash := crypto.SHA1.New()
 		_, _ = theHash.Write([]byte(tagValue))
@@ -211,8 +266,10 @@ func (b *BackendFilesystem) setETag(path string, tagValue string, replaceExistin
 		} else {
 			b.eTags[path] = "\"" + hex.EncodeToString([]byte(tagValue)) + "\""
 		}
+		if b.weakETags {
+			b.eTags[path] = "W/" + b.eTags[path]
+		}
 	}
-	b.syncer.Unlock()
 }
 
 func (b *BackendFilesystem) getFileObject(path string) (*FileObject, error) {
@@ -257,7 +314,11 @@ func (b *BackendFilesystem) directStats(path string) (size int64, modified time.
 			}
 			return -1, fstats.ModTime(), nil
 		} else {
-			if b.calculateETags {
+			if b.contentETags {
+				if !b.setContentETag(path, fstats) {
+					b.setETag(path, strconv.FormatInt(fstats.Size(), 10)+":"+fstats.ModTime().Format(http.TimeFormat), true)
+				}
+			} else if b.calculateETags {
 				b.setETag(path, strconv.FormatInt(fstats.Size(), 10)+":"+fstats.ModTime().Format(http.TimeFormat), false)
 			}
 			return fstats.Size(), fstats.ModTime(), nil
@@ -269,6 +330,17 @@ func (b *BackendFilesystem) directStats(path string) (size int64, modified time.
 
 func (b *BackendFilesystem) Purge(path string) (err error) {
 	b.syncer.Lock()
+	b.purgeObject(path)
+	if b.precompressedFiles {
+		for _, e := range precompressedExtensions {
+			b.purgeObject(path + e.extension)
+		}
+	}
+	b.syncer.Unlock()
+	return nil
+}
+
+func (b *BackendFilesystem) purgeObject(path string) {
 	if _, ok := b.fileObjects[path]; ok {
 		b.fileObjects[path] = nil
 	}
@@ -277,11 +349,24 @@ func (b *BackendFilesystem) Purge(path string) (err error) {
 			b.eTags[path] = ""
 		}
 	}
-	b.syncer.Unlock()
-	return nil
+}
+
+func (b *BackendFilesystem) PrecompressedEncodings(path string) (encodings []string) {
+	if !b.precompressedFiles {
+		return nil
+	}
+	for _, e := range precompressedExtensions {
+		if fStats, err := os.Stat(pth.Join(b.directoryPath, path+e.extension)); err == nil && !fStats.IsDir() {
+			encodings = append(encodings, e.encoding)
+		}
+	}
+	return encodings
 }
 
 func (b *BackendFilesystem) Exists(path string) (exists bool, listable bool) {
+	if b.isHiddenFile(path) {
+		return false, false
+	}
 	if fStats, err := os.Stat(pth.Join(b.directoryPath, path)); err == nil {
 		if fStats.IsDir() {
 			return b.directoryListing, true
@@ -301,12 +386,78 @@ func (b *BackendFilesystem) Exists(path string) (exists bool, listable bool) {
 
 func (b *BackendFilesystem) List(path string) (entries []string, err error) {
 	if dir, err := os.ReadDir(pth.Join(b.directoryPath, path)); err == nil {
-		contents := make([]string, len(dir))
-		for i, d := range dir {
-			contents[i] = d.Name()
+		contents := make([]string, 0, len(dir))
+		for _, d := range dir {
+			if b.isHiddenFile(d.Name()) {
+				continue
+			}
+			contents = append(contents, d.Name())
 		}
 		return contents, nil
 	} else {
 		return nil, err
 	}
 }
+
+func (b *BackendFilesystem) Store(path string, data io.Reader, size int64, contentType string) (err error) {
+	cleanPath := pth.Clean("/" + path)
+	if cleanPath == "/" {
+		return utils.NewStoreConflictError("object is a directory")
+	}
+	if b.isHiddenFile(cleanPath) {
+		return utils.NewStoreForbiddenError("object name reserved")
+	}
+	targetPath := pth.Join(b.directoryPath, cleanPath)
+	if fStats, err := os.Stat(targetPath); err == nil && fStats.IsDir() {
+		return utils.NewStoreConflictError("object is a directory")
+	}
+	err = os.MkdirAll(pth.Dir(targetPath), 0777)
+	if err != nil {
+		if errors.Is(err, syscall.ENOTDIR) || errors.Is(err, os.ErrExist) {
+			return utils.NewStoreConflictError("parent is not a directory")
+		}
+		return err
+	}
+	err = b.writeFileAtomic(targetPath, func(w io.Writer) error {
+		written, err := io.Copy(w, data)
+		if err == nil && size >= 0 && written != size {
+			err = errors.New("object length mismatch")
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if b.policyFiles {
+		err = b.storeContentType(cleanPath, contentType)
+		if err != nil {
+			return err
+		}
+	}
+	return b.Purge(path)
+}
+
+func (b *BackendFilesystem) isHiddenFile(path string) bool {
+	return (b.policyFiles && isPolicyFile(path)) || (b.eTagSidecars && isETagSidecar(path)) || strings.HasPrefix(pth.Base(path), uploadTempPrefix)
+}
+
+func (b *BackendFilesystem) writeFileAtomic(targetPath string, write func(w io.Writer) error) error {
+	tempFile, err := os.CreateTemp(pth.Dir(targetPath), uploadTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	err = write(tempFile)
+	if err == nil {
+		err = tempFile.Chmod(b.uploadFileMode)
+	}
+	if cErr := tempFile.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), targetPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+	}
+	return err
+}
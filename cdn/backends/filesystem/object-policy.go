@@ -0,0 +1,114 @@
+package filesystem
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mime"
+	"os"
+	pth "path"
+	"reflect"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strings"
+	"time"
+)
+
+const policyFileSuffix = ".snowmeta.yml"
+
+type policyFile struct {
+	size       int64
+	modifyTime time.Time
+	policy     conf.ObjectPolicyYaml
+}
+
+func isPolicyFile(path string) bool {
+	return strings.HasSuffix(pth.Base(path), policyFileSuffix)
+}
+
+func (b *BackendFilesystem) Policy(path string) (policy conf.ObjectPolicyYaml) {
+	if !b.policyFiles {
+		return conf.ObjectPolicyYaml{}
+	}
+	cleanPath := pth.Clean("/" + path)
+	theDirectory := cleanPath
+	if fStats, err := os.Stat(pth.Join(b.directoryPath, cleanPath)); err != nil || !fStats.IsDir() {
+		theDirectory = pth.Dir(cleanPath)
+	}
+	currentDirectory := "/"
+	policy = b.getPolicyFile(pth.Join(currentDirectory, policyFileSuffix))
+	for _, part := range strings.Split(strings.Trim(theDirectory, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		currentDirectory = pth.Join(currentDirectory, part)
+		policy = policy.Merge(b.getPolicyFile(pth.Join(currentDirectory, policyFileSuffix)))
+	}
+	if theDirectory != cleanPath {
+		policy = policy.Merge(b.getPolicyFile(cleanPath + policyFileSuffix))
+	}
+	return policy
+}
+
+func (b *BackendFilesystem) storeContentType(path string, contentType string) error {
+	if contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			contentType = ""
+		} else {
+			contentType = mime.FormatMediaType(mediaType, params)
+		}
+	}
+	if contentType == "" {
+		return nil
+	}
+	if naturalType := b.MimeType(path); naturalType != "" {
+		if mediaType, params, err := mime.ParseMediaType(naturalType); err == nil && mime.FormatMediaType(mediaType, params) == contentType {
+			contentType = ""
+		}
+	}
+	policyPath := path + policyFileSuffix
+	thePolicy := b.getPolicyFile(policyPath)
+	if thePolicy.ContentType == contentType {
+		return nil
+	}
+	thePolicy.ContentType = contentType
+	targetPath := pth.Join(b.directoryPath, policyPath)
+	if reflect.DeepEqual(thePolicy, conf.ObjectPolicyYaml{}) {
+		err := os.Remove(targetPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return b.writeFileAtomic(targetPath, func(w io.Writer) error {
+		return yaml.NewEncoder(w).Encode(thePolicy)
+	})
+}
+
+func (b *BackendFilesystem) getPolicyFile(path string) conf.ObjectPolicyYaml {
+	b.policySyncer.Lock()
+	defer b.policySyncer.Unlock()
+	fStats, err := os.Stat(pth.Join(b.directoryPath, path))
+	if err != nil || fStats.IsDir() {
+		delete(b.policies, path)
+		return conf.ObjectPolicyYaml{}
+	}
+	if cached := b.policies[path]; cached != nil && cached.size == fStats.Size() && cached.modifyTime.Equal(fStats.ModTime()) {
+		return cached.policy
+	}
+	theFile, err := os.Open(pth.Join(b.directoryPath, path))
+	if err != nil {
+		return conf.ObjectPolicyYaml{}
+	}
+	defer theFile.Close()
+	var thePolicy conf.ObjectPolicyYaml
+	if err = yaml.NewDecoder(theFile).Decode(&thePolicy); err != nil {
+		thePolicy = conf.ObjectPolicyYaml{}
+	}
+	b.policies[path] = &policyFile{
+		size:       fStats.Size(),
+		modifyTime: fStats.ModTime(),
+		policy:     thePolicy,
+	}
+	return thePolicy
+}
@@ -0,0 +1,83 @@
+package httpcache
+
+import (
+	"container/list"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"time"
+)
+
+const metadataSuffix = ".snowcache.yml"
+
+func NewCacheEntry(path string, size int64, eTag string, mimeType string, validated time.Time) *CacheEntry {
+	return &CacheEntry{
+		path:       path,
+		size:       size,
+		eTag:       eTag,
+		mimeType:   mimeType,
+		validated:  validated,
+		lastAccess: validated,
+	}
+}
+
+type CacheEntry struct {
+	path         string
+	size         int64
+	eTag         string
+	originETag   string
+	mimeType     string
+	validated    time.Time
+	lastAccess   time.Time
+	savedAccess  time.Time
+	revalidating bool
+	element      *list.Element
+}
+
+type cacheMetadata struct {
+	ETag       string    `yaml:"eTag,omitempty"`
+	OriginETag string    `yaml:"originETag,omitempty"`
+	MimeType   string    `yaml:"mimeType,omitempty"`
+	Validated  time.Time `yaml:"validated"`
+	LastAccess time.Time `yaml:"lastAccess,omitempty"`
+}
+
+func (e *CacheEntry) metadata() cacheMetadata {
+	return cacheMetadata{
+		ETag:       e.eTag,
+		OriginETag: e.originETag,
+		MimeType:   e.mimeType,
+		Validated:  e.validated,
+		LastAccess: e.lastAccess,
+	}
+}
+
+func readCacheMetadata(metadataPath string) (metadata cacheMetadata, err error) {
+	theFile, err := os.Open(metadataPath)
+	if err != nil {
+		return metadata, err
+	}
+	defer utils.MustClose(theFile)
+	err = yaml.NewDecoder(theFile).Decode(&metadata)
+	return metadata, err
+}
+
+func writeCacheMetadata(metadataPath string, metadata cacheMetadata) error {
+	partialFile, err := os.CreateTemp(filepath.Dir(metadataPath), partialPrefix+"*")
+	if err != nil {
+		return err
+	}
+	err = yaml.NewEncoder(partialFile).Encode(metadata)
+	cErr := partialFile.Close()
+	if err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(partialFile.Name(), metadataPath)
+	}
+	if err != nil {
+		_ = os.Remove(partialFile.Name())
+	}
+	return err
+}
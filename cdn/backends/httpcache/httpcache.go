@@ -0,0 +1,375 @@
+package httpcache
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	pth "path"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/filesystem"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/httporigin"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const partialPrefix = ".snowedin-partial-"
+
+// accessPersistInterval limits how often the last access time of a cached
+// object is written to its metadata sidecar.
+const accessPersistInterval = time.Minute
+
+func NewBackendHttpCache(confMap map[string]string) *BackendHttpCache {
+	if confMap["cacheDirectoryPath"] == "" {
+		return nil
+	}
+	directory, err := filepath.Abs(confMap["cacheDirectoryPath"])
+	if err != nil || os.MkdirAll(directory, 0777) != nil {
+		return nil
+	}
+	origin := httporigin.NewBackendHttp(confMap)
+	if origin == nil {
+		return nil
+	}
+	var mcs int64
+	if confMap["maxCacheSize"] != "" {
+		lmcs, err := strconv.ParseInt(confMap["maxCacheSize"], 10, 64)
+		if err == nil && lmcs > 0 {
+			mcs = lmcs
+		}
+	}
+	var rint = time.Minute
+	if confMap["revalidateInterval"] != "" {
+		lrint, err := time.ParseDuration(confMap["revalidateInterval"])
+		if err == nil && lrint >= 0 {
+			rint = lrint
+		}
+	}
+	fsConfMap := map[string]string{
+		"directoryPath":       directory,
+		"cachedHeaderBytes":   confMap["cachedHeaderBytes"],
+		"mimeTypeByExtension": confMap["mimeTypeByExtension"],
+		"calculateETags":      confMap["calculateETags"],
+	}
+	toReturn := &BackendHttpCache{
+		directoryPath:      directory,
+		maxCacheSize:       mcs,
+		revalidateInterval: rint,
+		accessPersist:      accessPersistInterval,
+		origin:             origin,
+		storage:            filesystem.NewBackendFilesystem(fsConfMap),
+		cacheEntries:       make(map[string]*CacheEntry),
+		cacheOrder:         list.New(),
+		syncer:             &sync.Mutex{},
+	}
+	toReturn.loadCacheEntries()
+	return toReturn
+}
+
+type BackendHttpCache struct {
+	directoryPath      string
+	maxCacheSize       int64
+	cacheSize          int64
+	revalidateInterval time.Duration
+	accessPersist      time.Duration
+	origin             *httporigin.BackendHttp
+	storage            *filesystem.BackendFilesystem
+	cacheEntries       map[string]*CacheEntry
+	cacheOrder         *list.List
+	syncer             *sync.Mutex
+}
+
+func (b *BackendHttpCache) ETag(path string) (eTag string) {
+	path = cleanPath(path)
+	if cEntry := b.getCacheEntry(path); cEntry != nil {
+		if cEntry.eTag != "" {
+			return cEntry.eTag
+		}
+		return b.storage.ETag(path)
+	}
+	return b.origin.ETag(path)
+}
+
+func (b *BackendHttpCache) MimeType(path string) (mimetype string) {
+	path = cleanPath(path)
+	if cEntry := b.getCacheEntry(path); cEntry != nil {
+		if cEntry.mimeType != "" {
+			return cEntry.mimeType
+		}
+		return b.storage.MimeType(path)
+	}
+	return b.origin.MimeType(path)
+}
+
+func (b *BackendHttpCache) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	path = cleanPath(path)
+	if b.getCacheEntry(path) != nil {
+		return b.storage.WriteDataRange(path, rw, index, length)
+	}
+	return b.origin.WriteDataRange(path, rw, index, length)
+}
+
+func (b *BackendHttpCache) WriteData(path string, rw io.Writer) (err error) {
+	path = cleanPath(path)
+	if b.getCacheEntry(path) != nil {
+		return b.storage.WriteData(path, rw)
+	}
+	size, modified, err := b.origin.Stats(path)
+	if err != nil {
+		return err
+	}
+	if (b.maxCacheSize > 0 && size > b.maxCacheSize) || isReservedPath(path) {
+		return b.origin.WriteData(path, rw)
+	}
+	targetPath := filepath.Join(b.directoryPath, filepath.FromSlash(path))
+	err = os.MkdirAll(filepath.Dir(targetPath), 0777)
+	if err != nil {
+		return b.origin.WriteData(path, rw)
+	}
+	partialFile, err := os.CreateTemp(filepath.Dir(targetPath), partialPrefix+"*")
+	if err != nil {
+		return b.origin.WriteData(path, rw)
+	}
+	cWriter := &cacheFileWriter{file: partialFile}
+	err = b.origin.WriteData(path, io.MultiWriter(rw, cWriter))
+	cErr := partialFile.Close()
+	if err != nil || cWriter.err != nil || cErr != nil || cWriter.length != size {
+		_ = os.Remove(partialFile.Name())
+		return err
+	}
+	if !modified.IsZero() {
+		_ = os.Chtimes(partialFile.Name(), modified, modified)
+	}
+	_, _, _, originETag, _ := b.origin.Validators(path)
+	cEntry := NewCacheEntry(path, size, b.origin.ETag(path), b.origin.MimeType(path), time.Now())
+	cEntry.originETag = originETag
+	b.syncer.Lock()
+	defer b.syncer.Unlock()
+	err = os.Rename(partialFile.Name(), targetPath)
+	if err != nil {
+		_ = os.Remove(partialFile.Name())
+		return nil
+	}
+	_ = b.storage.Purge(path)
+	if b.cacheEntries[path] != nil {
+		b.unlinkCacheEntry(path)
+	}
+	b.writeCacheMetadata(cEntry)
+	b.linkCacheEntry(cEntry)
+	b.evictCacheEntries()
+	return nil
+}
+
+func (b *BackendHttpCache) Stats(path string) (size int64, modified time.Time, err error) {
+	path = cleanPath(path)
+	if b.getCacheEntry(path) != nil {
+		return b.storage.Stats(path)
+	}
+	return b.origin.Stats(path)
+}
+
+func (b *BackendHttpCache) Purge(path string) (err error) {
+	path = cleanPath(path)
+	b.syncer.Lock()
+	if b.cacheEntries[path] != nil {
+		err = b.removeCacheEntry(path)
+	}
+	b.syncer.Unlock()
+	_ = b.origin.Purge(path)
+	return err
+}
+
+func (b *BackendHttpCache) Exists(path string) (exists bool, listable bool) {
+	path = cleanPath(path)
+	if cEntry := b.getCacheEntry(path); cEntry != nil && b.revalidateCacheEntry(cEntry) {
+		return true, false
+	}
+	return b.origin.Exists(path)
+}
+
+func (b *BackendHttpCache) List(path string) (entries []string, err error) {
+	return nil, errors.New("listing not supported")
+}
+
+func (b *BackendHttpCache) getCacheEntry(path string) *CacheEntry {
+	b.syncer.Lock()
+	defer b.syncer.Unlock()
+	cEntry := b.cacheEntries[path]
+	if cEntry != nil {
+		b.cacheOrder.MoveToFront(cEntry.element)
+		cEntry.lastAccess = time.Now()
+		if cEntry.lastAccess.Sub(cEntry.savedAccess) >= b.accessPersist {
+			b.writeCacheMetadata(cEntry)
+		}
+	}
+	return cEntry
+}
+
+func (b *BackendHttpCache) writeCacheMetadata(cEntry *CacheEntry) {
+	targetPath := filepath.Join(b.directoryPath, filepath.FromSlash(cEntry.path))
+	if writeCacheMetadata(targetPath+metadataSuffix, cEntry.metadata()) == nil {
+		cEntry.savedAccess = cEntry.lastAccess
+	}
+}
+
+func (b *BackendHttpCache) revalidateCacheEntry(cEntry *CacheEntry) bool {
+	b.syncer.Lock()
+	if b.cacheEntries[cEntry.path] != cEntry {
+		b.syncer.Unlock()
+		return false
+	}
+	if b.revalidateInterval == 0 || cEntry.revalidating || time.Since(cEntry.validated) < b.revalidateInterval {
+		b.syncer.Unlock()
+		return true
+	}
+	cEntry.revalidating = true
+	b.syncer.Unlock()
+
+	_ = b.origin.Purge(cEntry.path)
+	exists, size, modified, originETag, err := b.origin.Validators(cEntry.path)
+	targetPath := filepath.Join(b.directoryPath, filepath.FromSlash(cEntry.path))
+	var unchanged bool
+	if err == nil && exists && size == cEntry.size {
+		if originETag != "" && cEntry.originETag != "" {
+			unchanged = originETag == cEntry.originETag
+		} else if info, sErr := os.Stat(targetPath); sErr == nil && !modified.IsZero() {
+			unchanged = info.ModTime().Equal(modified)
+		}
+	}
+	var eTag, mimeType string
+	if unchanged {
+		eTag, mimeType = b.origin.ETag(cEntry.path), b.origin.MimeType(cEntry.path)
+	}
+
+	b.syncer.Lock()
+	defer b.syncer.Unlock()
+	cEntry.revalidating = false
+	if b.cacheEntries[cEntry.path] != cEntry {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	if !unchanged {
+		_ = b.removeCacheEntry(cEntry.path)
+		return false
+	}
+	cEntry.eTag, cEntry.mimeType, cEntry.originETag = eTag, mimeType, originETag
+	cEntry.validated = time.Now()
+	b.writeCacheMetadata(cEntry)
+	return true
+}
+
+func (b *BackendHttpCache) linkCacheEntry(cEntry *CacheEntry) {
+	cEntry.element = b.cacheOrder.PushFront(cEntry)
+	b.cacheEntries[cEntry.path] = cEntry
+	b.cacheSize += cEntry.size
+}
+
+func (b *BackendHttpCache) unlinkCacheEntry(path string) {
+	cEntry := b.cacheEntries[path]
+	delete(b.cacheEntries, path)
+	b.cacheOrder.Remove(cEntry.element)
+	b.cacheSize -= cEntry.size
+}
+
+func (b *BackendHttpCache) removeCacheEntry(path string) error {
+	b.unlinkCacheEntry(path)
+	_ = b.storage.Purge(path)
+	targetPath := filepath.Join(b.directoryPath, filepath.FromSlash(path))
+	err := os.Remove(targetPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Remove(targetPath + metadataSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *BackendHttpCache) evictCacheEntries() {
+	for b.maxCacheSize > 0 && b.cacheSize > b.maxCacheSize {
+		oldest := b.cacheOrder.Back()
+		if oldest == nil {
+			return
+		}
+		_ = b.removeCacheEntry(oldest.Value.(*CacheEntry).path)
+	}
+}
+
+func (b *BackendHttpCache) loadCacheEntries() {
+	var loaded []*CacheEntry
+	_ = filepath.WalkDir(b.directoryPath, func(fPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), partialPrefix) {
+			_ = os.Remove(fPath)
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), metadataSuffix) {
+			if _, err := os.Stat(strings.TrimSuffix(fPath, metadataSuffix)); errors.Is(err, fs.ErrNotExist) {
+				_ = os.Remove(fPath)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.directoryPath, fPath)
+		if err != nil {
+			return nil
+		}
+		cEntry := NewCacheEntry(cleanPath(filepath.ToSlash(rel)), info.Size(), "", "", time.Time{})
+		if metadata, err := readCacheMetadata(fPath + metadataSuffix); err == nil {
+			cEntry.eTag, cEntry.mimeType, cEntry.originETag = metadata.ETag, metadata.MimeType, metadata.OriginETag
+			cEntry.validated = metadata.Validated
+			cEntry.lastAccess = metadata.LastAccess
+		}
+		if cEntry.lastAccess.IsZero() {
+			cEntry.lastAccess = cEntry.validated
+		}
+		if cEntry.lastAccess.IsZero() {
+			cEntry.lastAccess = info.ModTime()
+		}
+		cEntry.savedAccess = cEntry.lastAccess
+		loaded = append(loaded, cEntry)
+		return nil
+	})
+	sort.SliceStable(loaded, func(i, j int) bool {
+		return loaded[i].lastAccess.Before(loaded[j].lastAccess)
+	})
+	for _, cEntry := range loaded {
+		b.linkCacheEntry(cEntry)
+	}
+	b.evictCacheEntries()
+}
+
+func cleanPath(path string) string {
+	return strings.TrimPrefix(pth.Clean("/"+path), "/")
+}
+
+func isReservedPath(path string) bool {
+	return strings.HasSuffix(path, metadataSuffix) || strings.HasPrefix(pth.Base(path), partialPrefix)
+}
+
+type cacheFileWriter struct {
+	file   *os.File
+	length int64
+	err    error
+}
+
+func (w *cacheFileWriter) Write(p []byte) (n int, err error) {
+	if w.err == nil {
+		written, err := w.file.Write(p)
+		w.length += int64(written)
+		w.err = err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,169 @@
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testOrigin struct {
+	mu      sync.Mutex
+	objects map[string]string
+	gets    int
+}
+
+func (o *testOrigin) set(path string, content string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.objects[path] = content
+}
+
+func (o *testOrigin) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	o.mu.Lock()
+	content, ok := o.objects[strings.TrimPrefix(req.URL.Path, "/")]
+	if req.Method == http.MethodGet {
+		o.gets++
+	}
+	o.mu.Unlock()
+	if !ok {
+		http.NotFound(rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "text/x-test")
+	rw.Header().Set("ETag", "\""+content+"\"")
+	http.ServeContent(rw, req, "", time.Time{}, strings.NewReader(content))
+}
+
+func newTestCache(t *testing.T, origin *httptest.Server, dir string, confMap map[string]string) *BackendHttpCache {
+	fullConfMap := map[string]string{"originUrl": origin.URL, "cacheDirectoryPath": dir}
+	for k, v := range confMap {
+		fullConfMap[k] = v
+	}
+	b := NewBackendHttpCache(fullConfMap)
+	if b == nil {
+		t.Fatal("failed to create the backend")
+	}
+	return b
+}
+
+func readObject(t *testing.T, b *BackendHttpCache, path string) string {
+	if exists, _ := b.Exists(path); !exists {
+		t.Fatalf("expected %s to exist", path)
+	}
+	buf := new(bytes.Buffer)
+	if err := b.WriteData(path, buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestHttpCacheKeepsMetadataAfterRestart(t *testing.T) {
+	origin := &testOrigin{objects: map[string]string{"a.txt": "hello"}}
+	srv := httptest.NewServer(origin)
+	defer srv.Close()
+	dir := t.TempDir()
+
+	b := newTestCache(t, srv, dir, map[string]string{"revalidateInterval": "0"})
+	if content := readObject(t, b, "a.txt"); content != "hello" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	b = newTestCache(t, srv, dir, map[string]string{"revalidateInterval": "0"})
+	if b.getCacheEntry("a.txt") == nil {
+		t.Fatal("expected the cached object to be loaded")
+	}
+	if eTag := b.ETag("a.txt"); eTag != "\"hello\"" {
+		t.Fatalf("expected the origin ETag, got %q", eTag)
+	}
+	if mimeType := b.MimeType("a.txt"); mimeType != "text/x-test" {
+		t.Fatalf("expected the origin mime type, got %q", mimeType)
+	}
+}
+
+func TestHttpCacheRevalidation(t *testing.T) {
+	origin := &testOrigin{objects: map[string]string{"a.txt": "hello"}}
+	srv := httptest.NewServer(origin)
+	defer srv.Close()
+
+	b := newTestCache(t, srv, t.TempDir(), map[string]string{"revalidateInterval": "1ms"})
+	readObject(t, b, "a.txt")
+	time.Sleep(5 * time.Millisecond)
+	if content := readObject(t, b, "a.txt"); content != "hello" || origin.gets != 1 {
+		t.Fatalf("expected the unchanged object to be served from the cache, got %q after %d fetches", content, origin.gets)
+	}
+
+	origin.set("a.txt", "world")
+	time.Sleep(5 * time.Millisecond)
+	if content := readObject(t, b, "a.txt"); content != "world" || origin.gets != 2 {
+		t.Fatalf("expected the changed object to be fetched again, got %q after %d fetches", content, origin.gets)
+	}
+	if eTag := b.ETag("a.txt"); eTag != "\"world\"" {
+		t.Fatalf("expected the new ETag, got %q", eTag)
+	}
+
+	origin.mu.Lock()
+	delete(origin.objects, "a.txt")
+	origin.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	if exists, _ := b.Exists("a.txt"); exists {
+		t.Fatal("expected the object removed from the origin to be dropped")
+	}
+}
+
+func TestHttpCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	origin := &testOrigin{objects: map[string]string{"a": "aaaa", "b": "bbbb", "c": "cccc"}}
+	srv := httptest.NewServer(origin)
+	defer srv.Close()
+
+	b := newTestCache(t, srv, t.TempDir(), map[string]string{"maxCacheSize": "8", "revalidateInterval": "0"})
+	readObject(t, b, "a")
+	readObject(t, b, "b")
+	readObject(t, b, "a")
+	readObject(t, b, "c")
+	if b.getCacheEntry("b") != nil {
+		t.Fatal("expected the least recently used object to be evicted")
+	}
+	if b.getCacheEntry("a") == nil || b.getCacheEntry("c") == nil {
+		t.Fatal("expected the recently used objects to be kept")
+	}
+	if b.cacheSize != 8 || b.cacheOrder.Len() != 2 {
+		t.Fatalf("unexpected cache size %d with %d entries", b.cacheSize, b.cacheOrder.Len())
+	}
+}
+
+func TestHttpCacheRestartKeepsAccessOrder(t *testing.T) {
+	origin := &testOrigin{objects: map[string]string{"a": "aaaa", "b": "bbbb", "c": "cccc"}}
+	srv := httptest.NewServer(origin)
+	defer srv.Close()
+	dir := t.TempDir()
+
+	b := newTestCache(t, srv, dir, map[string]string{"maxCacheSize": "8", "revalidateInterval": "0"})
+	b.accessPersist = 0
+	readObject(t, b, "a")
+	readObject(t, b, "b")
+	readObject(t, b, "a")
+
+	// the origin modified times are the reverse of the access order
+	oldTime, newTime := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a"), oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "b"), newTime, newTime); err != nil {
+		t.Fatal(err)
+	}
+
+	b = newTestCache(t, srv, dir, map[string]string{"maxCacheSize": "8", "revalidateInterval": "0"})
+	readObject(t, b, "c")
+	b.syncer.Lock()
+	aEntry, bEntry := b.cacheEntries["a"], b.cacheEntries["b"]
+	b.syncer.Unlock()
+	if aEntry == nil || bEntry != nil {
+		t.Fatal("expected the least recently accessed object to be evicted after a restart")
+	}
+}
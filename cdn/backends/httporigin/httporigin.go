@@ -0,0 +1,226 @@
+package httporigin
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	pth "path"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func NewBackendHttp(confMap map[string]string) *BackendHttp {
+	if confMap["originUrl"] == "" {
+		return nil
+	}
+	origin, err := url.Parse(confMap["originUrl"])
+	if err != nil || (origin.Scheme != "http" && origin.Scheme != "https") || origin.Host == "" {
+		return nil
+	}
+	var rtout = 30 * time.Second
+	if confMap["requestTimeout"] != "" {
+		lrtout, err := time.ParseDuration(confMap["requestTimeout"])
+		if err == nil && lrtout > 0 {
+			rtout = lrtout
+		}
+	}
+	var wmod = false
+	if confMap["watchModified"] != "" {
+		wmod, _ = strconv.ParseBool(confMap["watchModified"])
+	}
+	var mtbe = false
+	if confMap["mimeTypeByExtension"] != "" {
+		mtbe, _ = strconv.ParseBool(confMap["mimeTypeByExtension"])
+	}
+	var mttl = time.Minute
+	if confMap["metadataTTL"] != "" {
+		lmttl, err := time.ParseDuration(confMap["metadataTTL"])
+		if err == nil && lmttl >= 0 {
+			mttl = lmttl
+		}
+	}
+	var mmeta = 10000
+	if confMap["maxMetadataEntries"] != "" {
+		lmmeta, err := strconv.Atoi(confMap["maxMetadataEntries"])
+		if err == nil && lmmeta > 0 {
+			mmeta = lmmeta
+		}
+	}
+	var fetag = true
+	if confMap["forwardETags"] != "" {
+		lfetag, err := strconv.ParseBool(confMap["forwardETags"])
+		if err == nil {
+			fetag = lfetag
+		}
+	}
+	return &BackendHttp{
+		originUrl:           origin,
+		userAgent:           confMap["userAgent"],
+		watchModified:       wmod,
+		mimeTypeByExtension: mtbe,
+		forwardETags:        fetag,
+		client:              &http.Client{Timeout: rtout},
+		originObjects:       utils.NewMetadataCache[*OriginObject](mttl, mmeta),
+	}
+}
+
+type BackendHttp struct {
+	originUrl           *url.URL
+	userAgent           string
+	watchModified       bool
+	mimeTypeByExtension bool
+	forwardETags        bool
+	client              *http.Client
+	originObjects       *utils.MetadataCache[*OriginObject]
+}
+
+func (b *BackendHttp) ETag(path string) (eTag string) {
+	if b.forwardETags {
+		oObj, err := b.getOriginObject(path)
+		if err == nil {
+			return oObj.eTag
+		}
+	}
+	return ""
+}
+
+func (b *BackendHttp) MimeType(path string) (mimetype string) {
+	oObj, err := b.getOriginObject(path)
+	if err == nil && oObj.mimeType != "" {
+		return oObj.mimeType
+	}
+	pext := pth.Ext(path)
+	if b.mimeTypeByExtension && pext != "" {
+		return mime.TypeByExtension(pext)
+	} else {
+		return ""
+	}
+}
+
+func (b *BackendHttp) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	req, err := b.newOriginRequest(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(index, 10)+"-"+strconv.FormatInt(index+length-1, 10))
+	if oObj, err := b.getOriginObject(path); err == nil && oObj.eTag != "" && !strings.HasPrefix(oObj.eTag, "W/") {
+		req.Header.Set("If-Range", oObj.eTag)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utils.MustClose(resp.Body)
+	if resp.StatusCode == http.StatusPartialContent {
+		_, err = io.Copy(rw, io.LimitReader(resp.Body, length))
+		return err
+	} else if resp.StatusCode == http.StatusOK {
+		_, err = io.CopyN(io.Discard, resp.Body, index)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(rw, io.LimitReader(resp.Body, length))
+		return err
+	}
+	return errors.New("origin responded with " + resp.Status)
+}
+
+func (b *BackendHttp) WriteData(path string, rw io.Writer) (err error) {
+	req, err := b.newOriginRequest(http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer utils.MustClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("origin responded with " + resp.Status)
+	}
+	_, err = io.Copy(rw, resp.Body)
+	return err
+}
+
+func (b *BackendHttp) Stats(path string) (size int64, modified time.Time, err error) {
+	oObj, err := b.getOriginObject(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if !oObj.exists {
+		return 0, time.Time{}, errors.New("object does not exist")
+	}
+	if oObj.size < 0 {
+		return 0, time.Time{}, errors.New("origin did not provide the object length")
+	}
+	return oObj.size, oObj.modifyTime.UTC(), nil
+}
+
+func (b *BackendHttp) Purge(path string) (err error) {
+	b.originObjects.Delete(path)
+	return nil
+}
+
+func (b *BackendHttp) Exists(path string) (exists bool, listable bool) {
+	if b.watchModified {
+		b.originObjects.Delete(path)
+	}
+	oObj, err := b.getOriginObject(path)
+	if err != nil {
+		return false, false
+	}
+	return oObj.exists, false
+}
+
+func (b *BackendHttp) List(path string) (entries []string, err error) {
+	return nil, errors.New("listing not supported")
+}
+
+func (b *BackendHttp) Validators(path string) (exists bool, size int64, modified time.Time, eTag string, err error) {
+	oObj, err := b.getOriginObject(path)
+	if err != nil {
+		return false, 0, time.Time{}, "", err
+	}
+	return oObj.exists, oObj.size, oObj.modifyTime.UTC(), oObj.eTag, nil
+}
+
+func (b *BackendHttp) getOriginObject(path string) (*OriginObject, error) {
+	if oObj, ok := b.originObjects.Get(path); ok {
+		return oObj, nil
+	}
+	req, err := b.newOriginRequest(http.MethodHead, path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	utils.MustClose(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+		return nil, errors.New("origin responded with " + resp.Status)
+	}
+	oObj := NewOriginObject(resp)
+	if oObj.exists {
+		b.originObjects.Put(path, oObj)
+	}
+	return oObj, nil
+}
+
+func (b *BackendHttp) newOriginRequest(method string, path string) (*http.Request, error) {
+	target := *b.originUrl
+	target.Path = pth.Join("/", b.originUrl.Path, path)
+	target.RawPath = ""
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.userAgent != "" {
+		req.Header.Set("User-Agent", b.userAgent)
+	}
+	return req, nil
+}
@@ -0,0 +1,30 @@
+package httporigin
+
+import (
+	"net/http"
+	"time"
+)
+
+func NewOriginObject(resp *http.Response) *OriginObject {
+	var modTime time.Time
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if parsed, err := http.ParseTime(lm); err == nil {
+			modTime = parsed
+		}
+	}
+	return &OriginObject{
+		exists:     resp.StatusCode == http.StatusOK,
+		size:       resp.ContentLength,
+		modifyTime: modTime,
+		eTag:       resp.Header.Get("ETag"),
+		mimeType:   resp.Header.Get("Content-Type"),
+	}
+}
+
+type OriginObject struct {
+	exists     bool
+	size       int64
+	modifyTime time.Time
+	eTag       string
+	mimeType   string
+}
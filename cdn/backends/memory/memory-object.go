@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	pth "path"
+	"time"
+)
+
+func NewMemoryObject(data []byte, modifiedTime time.Time, mimeTypeByExtension bool, path string) *MemoryObject {
+	theSum := sha256.Sum256(data)
+	theMimeType := ""
+	if pext := pth.Ext(path); mimeTypeByExtension && pext != "" {
+		theMimeType = mime.TypeByExtension(pext)
+	}
+	if theMimeType == "" && len(data) > 0 {
+		theMimeType = http.DetectContentType(data)
+	}
+	return &MemoryObject{
+		data:       data,
+		modifyTime: modifiedTime,
+		eTag:       "\"" + hex.EncodeToString(theSum[:]) + "\"",
+		mimeType:   theMimeType,
+	}
+}
+
+type MemoryObject struct {
+	data       []byte
+	modifyTime time.Time
+	eTag       string
+	mimeType   string
+}
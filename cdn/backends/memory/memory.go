@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	pth "path"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func NewBackendMemory(confMap map[string]string) *BackendMemory {
+	wdir, _ := os.Getwd()
+	directory := wdir
+	if confMap["directoryPath"] != "" {
+		directory = confMap["directoryPath"]
+		fstat, err := os.Stat(directory)
+		if err != nil || !fstat.IsDir() {
+			directory = wdir
+		}
+	}
+	var files []string
+	if confMap["files"] != "" {
+		for _, f := range strings.Split(confMap["files"], ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				files = append(files, f)
+			}
+		}
+	}
+	var maxs int64
+	if confMap["maxObjectSize"] != "" {
+		lmaxs, err := strconv.ParseInt(confMap["maxObjectSize"], 10, 64)
+		if err == nil && lmaxs > 0 {
+			maxs = lmaxs
+		}
+	}
+	var mtbe = true
+	if confMap["mimeTypeByExtension"] != "" {
+		lmtbe, err := strconv.ParseBool(confMap["mimeTypeByExtension"])
+		if err == nil {
+			mtbe = lmtbe
+		}
+	}
+	var dirl = false
+	if confMap["listDirectories"] != "" {
+		dirl, _ = strconv.ParseBool(confMap["listDirectories"])
+	}
+	toReturn := &BackendMemory{
+		directoryPath:       directory,
+		files:               files,
+		manifestPath:        confMap["manifestPath"],
+		maxObjectSize:       maxs,
+		mimeTypeByExtension: mtbe,
+		directoryListing:    dirl,
+		syncer:              &sync.RWMutex{},
+	}
+	toReturn.reload()
+	return toReturn
+}
+
+type BackendMemory struct {
+	directoryPath       string
+	files               []string
+	manifestPath        string
+	maxObjectSize       int64
+	mimeTypeByExtension bool
+	directoryListing    bool
+	memoryObjects       map[string]*MemoryObject
+	directories         map[string][]string
+	syncer              *sync.RWMutex
+}
+
+func (b *BackendMemory) ETag(path string) (eTag string) {
+	if mObj := b.getMemoryObject(path); mObj != nil {
+		return mObj.eTag
+	}
+	return ""
+}
+
+func (b *BackendMemory) MimeType(path string) (mimetype string) {
+	if mObj := b.getMemoryObject(path); mObj != nil {
+		return mObj.mimeType
+	}
+	return ""
+}
+
+func (b *BackendMemory) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	mObj := b.getMemoryObject(path)
+	if mObj == nil {
+		return errors.New("object not writeable")
+	}
+	if index < 0 || length < 0 || index+length > int64(len(mObj.data)) {
+		return errors.New("range out of bounds")
+	}
+	_, err = rw.Write(mObj.data[index : index+length])
+	return err
+}
+
+func (b *BackendMemory) WriteData(path string, rw io.Writer) (err error) {
+	mObj := b.getMemoryObject(path)
+	if mObj == nil {
+		return errors.New("object not writeable")
+	}
+	_, err = rw.Write(mObj.data)
+	return err
+}
+
+func (b *BackendMemory) Stats(path string) (size int64, modified time.Time, err error) {
+	if mObj := b.getMemoryObject(path); mObj != nil {
+		return int64(len(mObj.data)), mObj.modifyTime.UTC(), nil
+	}
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	if _, ok := b.directories[cleanPath(path)]; ok {
+		return -1, time.Time{}, nil
+	}
+	return 0, time.Time{}, errors.New("object does not exist")
+}
+
+func (b *BackendMemory) Purge(path string) (err error) {
+	b.reload()
+	return nil
+}
+
+func (b *BackendMemory) Exists(path string) (exists bool, listable bool) {
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	path = cleanPath(path)
+	if b.memoryObjects[path] != nil {
+		return true, false
+	}
+	if _, ok := b.directories[path]; ok {
+		return b.directoryListing, true
+	}
+	return false, false
+}
+
+func (b *BackendMemory) List(path string) (entries []string, err error) {
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	names, ok := b.directories[cleanPath(path)]
+	if !ok {
+		return nil, errors.New("directory does not exist")
+	}
+	contents := make([]string, len(names))
+	copy(contents, names)
+	return contents, nil
+}
+
+func (b *BackendMemory) getMemoryObject(path string) *MemoryObject {
+	b.syncer.RLock()
+	defer b.syncer.RUnlock()
+	return b.memoryObjects[cleanPath(path)]
+}
+
+func (b *BackendMemory) reload() {
+	memoryObjects := make(map[string]*MemoryObject)
+	directories := map[string][]string{"": {}}
+	addObject := func(path string) {
+		path = cleanPath(path)
+		if path == "" || memoryObjects[path] != nil {
+			return
+		}
+		fPath := filepath.Join(b.directoryPath, filepath.FromSlash(path))
+		fStats, err := os.Stat(fPath)
+		if err != nil || !fStats.Mode().IsRegular() || (b.maxObjectSize > 0 && fStats.Size() > b.maxObjectSize) {
+			return
+		}
+		data, err := os.ReadFile(fPath)
+		if err != nil {
+			return
+		}
+		memoryObjects[path] = NewMemoryObject(data, fStats.ModTime(), b.mimeTypeByExtension, path)
+		for p := path; p != ""; {
+			parent := pth.Dir(p)
+			if parent == "." {
+				parent = ""
+			}
+			_, seen := directories[parent]
+			directories[parent] = appendName(directories[parent], pth.Base(p))
+			if seen {
+				break
+			}
+			p = parent
+		}
+	}
+
+	if len(b.files) == 0 && b.manifestPath == "" {
+		_ = filepath.WalkDir(b.directoryPath, func(fPath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if rel, err := filepath.Rel(b.directoryPath, fPath); err == nil {
+				addObject(filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	} else {
+		for _, f := range b.files {
+			addObject(f)
+		}
+		if b.manifestPath != "" {
+			if manifest, err := os.Open(b.manifestPath); err == nil {
+				scanner := bufio.NewScanner(manifest)
+				for scanner.Scan() {
+					if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+						addObject(line)
+					}
+				}
+				utils.MustClose(manifest)
+			}
+		}
+	}
+	for _, names := range directories {
+		sort.Strings(names)
+	}
+
+	b.syncer.Lock()
+	b.memoryObjects = memoryObjects
+	b.directories = directories
+	b.syncer.Unlock()
+}
+
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+func cleanPath(path string) string {
+	return strings.TrimPrefix(pth.Clean("/"+path), "/")
+}
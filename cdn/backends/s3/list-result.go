@@ -0,0 +1,19 @@
+package s3
+
+import "encoding/xml"
+
+type ListBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken"`
+	Contents              []ListContent  `xml:"Contents"`
+	CommonPrefixes        []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+type ListContent struct {
+	Key string `xml:"Key"`
+}
+
+type CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"net/http"
+	"time"
+)
+
+func NewS3Object(resp *http.Response) *S3Object {
+	var modTime time.Time
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if parsed, err := http.ParseTime(lm); err == nil {
+			modTime = parsed
+		}
+	}
+	return &S3Object{
+		size:       resp.ContentLength,
+		modifyTime: modTime,
+		eTag:       resp.Header.Get("ETag"),
+		mimeType:   resp.Header.Get("Content-Type"),
+	}
+}
+
+func NewS3DirectoryObject() *S3Object {
+	return &S3Object{
+		size:      -1,
+		directory: true,
+	}
+}
+
+type S3Object struct {
+	size       int64
+	modifyTime time.Time
+	eTag       string
+	mimeType   string
+	directory  bool
+}
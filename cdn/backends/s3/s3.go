@@ -0,0 +1,328 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	pth "path"
+	"snow.mrmelon54.xyz/snowedin/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func NewBackendS3(confMap map[string]string) *BackendS3 {
+	if confMap["endpoint"] == "" || confMap["bucket"] == "" {
+		return nil
+	}
+	endpoint, err := url.Parse(confMap["endpoint"])
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil
+	}
+	var region = "us-east-1"
+	if confMap["region"] != "" {
+		region = confMap["region"]
+	}
+	var pstyle = true
+	if confMap["pathStyle"] != "" {
+		lpstyle, err := strconv.ParseBool(confMap["pathStyle"])
+		if err == nil {
+			pstyle = lpstyle
+		}
+	}
+	var rtout = 30 * time.Second
+	if confMap["requestTimeout"] != "" {
+		lrtout, err := time.ParseDuration(confMap["requestTimeout"])
+		if err == nil && lrtout > 0 {
+			rtout = lrtout
+		}
+	}
+	var wmod = false
+	if confMap["watchModified"] != "" {
+		wmod, _ = strconv.ParseBool(confMap["watchModified"])
+	}
+	var mtbe = false
+	if confMap["mimeTypeByExtension"] != "" {
+		mtbe, _ = strconv.ParseBool(confMap["mimeTypeByExtension"])
+	}
+	var dirl = false
+	if confMap["listDirectories"] != "" {
+		dirl, _ = strconv.ParseBool(confMap["listDirectories"])
+	}
+	var mttl = time.Minute
+	if confMap["metadataTTL"] != "" {
+		lmttl, err := time.ParseDuration(confMap["metadataTTL"])
+		if err == nil && lmttl >= 0 {
+			mttl = lmttl
+		}
+	}
+	var mmeta = 10000
+	if confMap["maxMetadataEntries"] != "" {
+		lmmeta, err := strconv.Atoi(confMap["maxMetadataEntries"])
+		if err == nil && lmmeta > 0 {
+			mmeta = lmmeta
+		}
+	}
+	prefix := strings.Trim(confMap["prefix"], "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &BackendS3{
+		endpoint:            endpoint,
+		bucket:              confMap["bucket"],
+		prefix:              prefix,
+		pathStyle:           pstyle,
+		watchModified:       wmod,
+		mimeTypeByExtension: mtbe,
+		directoryListing:    dirl,
+		signer:              NewSigner(confMap["accessKeyId"], confMap["secretAccessKey"], confMap["sessionToken"], region),
+		client:              &http.Client{Timeout: rtout},
+		s3Objects:           utils.NewMetadataCache[*S3Object](mttl, mmeta),
+	}
+}
+
+type BackendS3 struct {
+	endpoint            *url.URL
+	bucket              string
+	prefix              string
+	pathStyle           bool
+	watchModified       bool
+	mimeTypeByExtension bool
+	directoryListing    bool
+	signer              *Signer
+	client              *http.Client
+	s3Objects           *utils.MetadataCache[*S3Object]
+}
+
+func (b *BackendS3) ETag(path string) (eTag string) {
+	sObj, err := b.getS3Object(path)
+	if err == nil && sObj != nil {
+		return sObj.eTag
+	}
+	return ""
+}
+
+func (b *BackendS3) MimeType(path string) (mimetype string) {
+	sObj, err := b.getS3Object(path)
+	if err == nil && sObj != nil && sObj.mimeType != "" {
+		return sObj.mimeType
+	}
+	pext := pth.Ext(path)
+	if b.mimeTypeByExtension && pext != "" {
+		return mime.TypeByExtension(pext)
+	} else {
+		return ""
+	}
+}
+
+func (b *BackendS3) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	req, err := b.newS3Request(http.MethodGet, b.objectKey(path), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(index, 10)+"-"+strconv.FormatInt(index+length-1, 10))
+	resp, err := b.doS3Request(req)
+	if err != nil {
+		return err
+	}
+	defer utils.MustClose(resp.Body)
+	if resp.StatusCode == http.StatusPartialContent {
+		_, err = io.Copy(rw, io.LimitReader(resp.Body, length))
+		return err
+	} else if resp.StatusCode == http.StatusOK {
+		_, err = io.CopyN(io.Discard, resp.Body, index)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(rw, io.LimitReader(resp.Body, length))
+		return err
+	}
+	return errors.New("s3 responded with " + resp.Status)
+}
+
+func (b *BackendS3) WriteData(path string, rw io.Writer) (err error) {
+	req, err := b.newS3Request(http.MethodGet, b.objectKey(path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.doS3Request(req)
+	if err != nil {
+		return err
+	}
+	defer utils.MustClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("s3 responded with " + resp.Status)
+	}
+	_, err = io.Copy(rw, resp.Body)
+	return err
+}
+
+func (b *BackendS3) Stats(path string) (size int64, modified time.Time, err error) {
+	sObj, err := b.getS3Object(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if sObj == nil {
+		return 0, time.Time{}, errors.New("object does not exist")
+	}
+	if !sObj.directory && sObj.size < 0 {
+		return 0, time.Time{}, errors.New("s3 did not provide the object length")
+	}
+	return sObj.size, sObj.modifyTime.UTC(), nil
+}
+
+func (b *BackendS3) Purge(path string) (err error) {
+	b.s3Objects.Delete(path)
+	return nil
+}
+
+func (b *BackendS3) Exists(path string) (exists bool, listable bool) {
+	if b.watchModified {
+		b.s3Objects.Delete(path)
+	}
+	sObj, err := b.getS3Object(path)
+	if err != nil || sObj == nil {
+		return false, false
+	}
+	if sObj.directory {
+		return b.directoryListing, true
+	}
+	return true, false
+}
+
+func (b *BackendS3) List(path string) (entries []string, err error) {
+	dirPrefix := b.directoryPrefix(path)
+	contents := make([]string, 0)
+	continuationToken := ""
+	for {
+		result, err := b.listObjects(dirPrefix, continuationToken, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.CommonPrefixes {
+			if name := strings.TrimSuffix(strings.TrimPrefix(c.Prefix, dirPrefix), "/"); name != "" {
+				contents = append(contents, name)
+			}
+		}
+		for _, c := range result.Contents {
+			if name := strings.TrimPrefix(c.Key, dirPrefix); name != "" {
+				contents = append(contents, name)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+	return contents, nil
+}
+
+func (b *BackendS3) getS3Object(path string) (*S3Object, error) {
+	sObj, ok := b.s3Objects.Get(path)
+	if ok {
+		return sObj, nil
+	}
+	if key := b.objectKey(path); key != "" && !strings.HasSuffix(key, "/") {
+		req, err := b.newS3Request(http.MethodHead, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := b.doS3Request(req)
+		if err != nil {
+			return nil, err
+		}
+		utils.MustClose(resp.Body)
+		if resp.StatusCode == http.StatusOK {
+			sObj = NewS3Object(resp)
+		} else if resp.StatusCode != http.StatusNotFound {
+			return nil, errors.New("s3 responded with " + resp.Status)
+		}
+	}
+	if sObj == nil && b.directoryListing {
+		result, err := b.listObjects(b.directoryPrefix(path), "", 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Contents) > 0 || len(result.CommonPrefixes) > 0 {
+			sObj = NewS3DirectoryObject()
+		}
+	}
+	if sObj != nil {
+		b.s3Objects.Put(path, sObj)
+	}
+	return sObj, nil
+}
+
+func (b *BackendS3) listObjects(prefix string, continuationToken string, maxKeys int) (*ListBucketResult, error) {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("delimiter", "/")
+	query.Set("prefix", prefix)
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	req, err := b.newS3Request(http.MethodGet, "", query)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.doS3Request(req)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.MustClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("s3 responded with " + resp.Status)
+	}
+	result := &ListBucketResult{}
+	err = xml.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *BackendS3) objectKey(path string) string {
+	path = strings.TrimPrefix(pth.Clean("/"+path), "/")
+	return b.prefix + path
+}
+
+func (b *BackendS3) directoryPrefix(path string) string {
+	key := b.objectKey(path)
+	if key != "" && !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	return key
+}
+
+func (b *BackendS3) newS3Request(method string, key string, query url.Values) (*http.Request, error) {
+	target := *b.endpoint
+	basePath := strings.TrimSuffix(b.endpoint.EscapedPath(), "/")
+	if b.pathStyle {
+		basePath += "/" + uriEncode(b.bucket, true)
+	} else {
+		target.Host = b.bucket + "." + b.endpoint.Host
+	}
+	escapedPath := basePath + "/" + uriEncode(key, false)
+	unescapedPath, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return nil, err
+	}
+	target.Path = unescapedPath
+	target.RawPath = escapedPath
+	target.RawQuery = ""
+	if query != nil {
+		target.RawQuery = getCanonicalQuery(query)
+	}
+	return http.NewRequest(method, target.String(), nil)
+}
+
+func (b *BackendS3) doS3Request(req *http.Request) (*http.Response, error) {
+	b.signer.Sign(req, time.Now())
+	return b.client.Do(req)
+}
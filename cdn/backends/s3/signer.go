@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func NewSigner(accessKeyId string, secretAccessKey string, sessionToken string, region string) *Signer {
+	return &Signer{
+		accessKeyId:     accessKeyId,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		region:          region,
+	}
+}
+
+type Signer struct {
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+}
+
+func (s *Signer) Sign(req *http.Request, signTime time.Time) {
+	amzDate := signTime.UTC().Format("20060102T150405Z")
+	shortDate := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	if s.accessKeyId == "" {
+		return
+	}
+
+	signedHeaders, canonicalHeaders := getCanonicalHeaders(req)
+	canonicalRequest := req.Method + "\n" +
+		req.URL.EscapedPath() + "\n" +
+		getCanonicalQuery(req.URL.Query()) + "\n" +
+		canonicalHeaders + "\n" +
+		signedHeaders + "\n" +
+		emptyPayloadHash
+	scope := shortDate + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	signingKey := hmacSum([]byte("AWS4"+s.secretAccessKey), shortDate)
+	signingKey = hmacSum(signingKey, s.region)
+	signingKey = hmacSum(signingKey, "s3")
+	signingKey = hmacSum(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSum(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKeyId+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func getCanonicalHeaders(req *http.Request) (signedHeaders string, canonicalHeaders string) {
+	values := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		values["host"] = req.Host
+	}
+	for k := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "range" || strings.HasPrefix(lk, "x-amz-") {
+			values[lk] = strings.Join(strings.Fields(req.Header.Get(k)), " ")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		canonicalHeaders += k + ":" + values[k] + "\n"
+	}
+	return strings.Join(names, ";"), canonicalHeaders
+}
+
+func getCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := query[k]
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func uriEncode(stringIn string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, c := range []byte(stringIn) {
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return sb.String()
+}
+
+func hashHex(data []byte) string {
+	theSum := sha256.Sum256(data)
+	return hex.EncodeToString(theSum[:])
+}
+
+func hmacSum(key []byte, data string) []byte {
+	theHash := hmac.New(sha256.New, key)
+	_, _ = theHash.Write([]byte(data))
+	return theHash.Sum(nil)
+}
@@ -0,0 +1,195 @@
+package union
+
+import (
+	"errors"
+	"io"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	snowutils "snow.mrmelon54.xyz/snowedin/utils"
+	"sort"
+	"time"
+)
+
+// ownerCacheTTL bounds how long a cached owner is trusted so objects moved
+// between children outside of Purge and Store are eventually picked up.
+const ownerCacheTTL = time.Minute
+
+func NewBackendUnion(children []backends.Backend) *BackendUnion {
+	if len(children) == 0 {
+		return nil
+	}
+	return &BackendUnion{
+		children: children,
+		owners:   snowutils.NewMetadataCache[backends.Backend](ownerCacheTTL, 10000),
+	}
+}
+
+type BackendUnion struct {
+	children []backends.Backend
+	owners   *snowutils.MetadataCache[backends.Backend]
+}
+
+func (b *BackendUnion) ETag(path string) (eTag string) {
+	if child := b.getOwner(path); child != nil {
+		return child.ETag(path)
+	}
+	return ""
+}
+
+func (b *BackendUnion) MimeType(path string) (mimetype string) {
+	if child := b.getOwner(path); child != nil {
+		return child.MimeType(path)
+	}
+	return ""
+}
+
+func (b *BackendUnion) WriteDataRange(path string, rw io.Writer, index int64, length int64) (err error) {
+	if child := b.getOwner(path); child != nil {
+		return child.WriteDataRange(path, rw, index, length)
+	}
+	return errors.New("object does not exist")
+}
+
+func (b *BackendUnion) WriteData(path string, rw io.Writer) (err error) {
+	if child := b.getOwner(path); child != nil {
+		return child.WriteData(path, rw)
+	}
+	return errors.New("object does not exist")
+}
+
+func (b *BackendUnion) Stats(path string) (size int64, modified time.Time, err error) {
+	if child := b.getOwner(path); child != nil {
+		return child.Stats(path)
+	}
+	return 0, time.Time{}, errors.New("object does not exist")
+}
+
+func (b *BackendUnion) Purge(path string) (err error) {
+	b.forgetOwners(path)
+	owner := b.findOwner(path)
+	if owner == nil {
+		for _, child := range b.children {
+			if cErr := child.Purge(path); cErr != nil && err == nil {
+				err = cErr
+			}
+		}
+		return err
+	}
+	err = owner.Purge(path)
+	for _, e := range utils.SupportedEncodings {
+		variant := path + utils.GetEncodingExtension(e)
+		if vOwner := b.findOwner(variant); vOwner != nil && vOwner != owner {
+			if vErr := vOwner.Purge(variant); vErr != nil && err == nil {
+				err = vErr
+			}
+		}
+	}
+	return err
+}
+
+func (b *BackendUnion) Exists(path string) (exists bool, listable bool) {
+	for _, child := range b.children {
+		cExists, cListable := child.Exists(path)
+		if cExists {
+			b.owners.Put(path, child)
+			return true, cListable
+		}
+		listable = listable || cListable
+	}
+	b.owners.Delete(path)
+	return false, listable
+}
+
+func (b *BackendUnion) Store(path string, data io.Reader, size int64, contentType string) (err error) {
+	owner := b.getOwner(path)
+	for _, child := range b.children {
+		if wChild, ok := child.(backends.WritableBackend); ok {
+			err = wChild.Store(path, data, size, contentType)
+			b.forgetOwners(path)
+			if err == nil {
+				b.owners.Put(path, child)
+			}
+			return err
+		}
+		if child == owner {
+			break
+		}
+	}
+	return snowutils.NewStoreForbiddenError("object provided by a read-only backend")
+}
+
+func (b *BackendUnion) Policy(path string) (policy conf.ObjectPolicyYaml) {
+	if pChild, ok := b.getOwner(path).(backends.PolicyBackend); ok {
+		return pChild.Policy(path)
+	}
+	return conf.ObjectPolicyYaml{}
+}
+
+func (b *BackendUnion) PrecompressedEncodings(path string) (encodings []string) {
+	owner := b.getOwner(path)
+	if pChild, ok := owner.(backends.PrecompressedBackend); ok {
+		encodings = pChild.PrecompressedEncodings(path)
+		for _, e := range encodings {
+			b.owners.Put(path+utils.GetEncodingExtension(e), owner)
+		}
+	}
+	return encodings
+}
+
+func (b *BackendUnion) List(path string) (entries []string, err error) {
+	seen := make(map[string]bool)
+	contents := make([]string, 0)
+	listed := false
+	for _, child := range b.children {
+		if cExists, cListable := child.Exists(path); !cExists || !cListable {
+			continue
+		}
+		cEntries, cErr := child.List(path)
+		if cErr != nil {
+			if err == nil {
+				err = cErr
+			}
+			continue
+		}
+		listed = true
+		for _, e := range cEntries {
+			if !seen[e] {
+				seen[e] = true
+				contents = append(contents, e)
+			}
+		}
+	}
+	if !listed {
+		if err == nil {
+			err = errors.New("directory does not exist")
+		}
+		return nil, err
+	}
+	sort.Strings(contents)
+	return contents, nil
+}
+
+func (b *BackendUnion) getOwner(path string) backends.Backend {
+	if owner, ok := b.owners.Get(path); ok {
+		return owner
+	}
+	return b.findOwner(path)
+}
+
+func (b *BackendUnion) findOwner(path string) backends.Backend {
+	for _, child := range b.children {
+		if cExists, _ := child.Exists(path); cExists {
+			b.owners.Put(path, child)
+			return child
+		}
+	}
+	return nil
+}
+
+func (b *BackendUnion) forgetOwners(path string) {
+	b.owners.Delete(path)
+	for _, e := range utils.SupportedEncodings {
+		b.owners.Delete(path + utils.GetEncodingExtension(e))
+	}
+}
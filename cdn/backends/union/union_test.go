@@ -0,0 +1,120 @@
+package union
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends"
+	"snow.mrmelon54.xyz/snowedin/cdn/backends/filesystem"
+	snowutils "snow.mrmelon54.xyz/snowedin/utils"
+	"testing"
+)
+
+// readOnlyBackend hides Store from the wrapped backend and counts purges.
+type readOnlyBackend struct {
+	backends.Backend
+	purges int
+}
+
+func (b *readOnlyBackend) Purge(path string) error {
+	b.purges++
+	return b.Backend.Purge(path)
+}
+
+func newTestChild(t *testing.T, files map[string]string) (*filesystem.BackendFilesystem, string) {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filesystem.NewBackendFilesystem(map[string]string{"directoryPath": dir, "policyFiles": "true"}), dir
+}
+
+func TestUnionOwnerLookup(t *testing.T) {
+	first, firstDir := newTestChild(t, map[string]string{"a.txt": "first"})
+	second, secondDir := newTestChild(t, map[string]string{"a.txt": "second", "b.txt": "only second"})
+	firstRO := &readOnlyBackend{Backend: first}
+	secondRO := &readOnlyBackend{Backend: second}
+	b := NewBackendUnion([]backends.Backend{firstRO, secondRO})
+
+	buf := new(bytes.Buffer)
+	if err := b.WriteData("a.txt", buf); err != nil || buf.String() != "first" {
+		t.Fatalf("expected the first child to own a.txt, got %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err := b.WriteData("b.txt", buf); err != nil || buf.String() != "only second" {
+		t.Fatalf("expected the second child to own b.txt, got %q: %v", buf.String(), err)
+	}
+
+	if err := b.Purge("b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if firstRO.purges != 0 || secondRO.purges != 1 {
+		t.Fatalf("expected only the owning child to be purged, got %d and %d", firstRO.purges, secondRO.purges)
+	}
+
+	// move a.txt from the first child to the second and purge it
+	if err := os.Remove(filepath.Join(firstDir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(secondDir, "a.txt"), []byte("moved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Purge("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := b.WriteData("a.txt", buf); err != nil || buf.String() != "moved" {
+		t.Fatalf("expected the purged owner to be looked up again, got %q: %v", buf.String(), err)
+	}
+
+	if exists, _ := b.Exists("missing.txt"); exists {
+		t.Fatal("expected missing.txt to not exist")
+	}
+	if err := b.WriteData("missing.txt", buf); err == nil {
+		t.Fatal("expected an error for a missing object")
+	}
+}
+
+func TestUnionStoreForwarding(t *testing.T) {
+	readOnly, _ := newTestChild(t, map[string]string{"ro.txt": "read only"})
+	writable, writableDir := newTestChild(t, nil)
+	b := NewBackendUnion([]backends.Backend{&readOnlyBackend{Backend: readOnly}, writable})
+
+	if err := b.Store("new.txt", bytes.NewReader([]byte("stored")), 6, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(filepath.Join(writableDir, "new.txt")); err != nil || string(content) != "stored" {
+		t.Fatalf("expected the object to be stored in the writable child, got %q: %v", content, err)
+	}
+	if policy := b.Policy("new.txt"); policy.ContentType != "text/plain" {
+		t.Fatalf("expected the stored content type to be forwarded, got %q", policy.ContentType)
+	}
+
+	err := b.Store("ro.txt", bytes.NewReader([]byte("x")), 1, "")
+	var storeErr *snowutils.StoreError
+	if !errors.As(err, &storeErr) || storeErr.Conflict {
+		t.Fatalf("expected a forbidden error when overwriting a read-only object, got %v", err)
+	}
+}
+
+func TestUnionPolicyForwarding(t *testing.T) {
+	first, _ := newTestChild(t, map[string]string{"a.txt": "first", "a.txt.snowmeta.yml": "cacheControl: first\n"})
+	second, _ := newTestChild(t, map[string]string{"a.txt": "second", "a.txt.snowmeta.yml": "cacheControl: second\n", "b.txt": "b", "b.txt.snowmeta.yml": "cacheControl: second-b\n"})
+	b := NewBackendUnion([]backends.Backend{first, second})
+
+	if policy := b.Policy("a.txt"); policy.CacheControl != "first" {
+		t.Fatalf("expected the first child policy, got %q", policy.CacheControl)
+	}
+	if policy := b.Policy("b.txt"); policy.CacheControl != "second-b" {
+		t.Fatalf("expected the second child policy, got %q", policy.CacheControl)
+	}
+	if policy := b.Policy("missing.txt"); policy.CacheControl != "" {
+		t.Fatalf("expected an empty policy for a missing object, got %q", policy.CacheControl)
+	}
+}
@@ -1,19 +1,62 @@
 package cdn
 
 import (
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/cdn/objectcache"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
 	"snow.mrmelon54.xyz/snowedin/conf"
+	"strconv"
+	"time"
 )
 
 func New(config conf.ConfigYaml) *CDN {
-	toReturn := &CDN{Config: config}
+	config.ExpandAddressGroups()
+	toReturn := &CDN{
+		Config:           config,
+		GlobalLimits:     limits.NewGlobalLimits(config.GlobalLimits),
+		BandwidthBuckets: limits.NewBandwidthBuckets(),
+		ObjectCache:      objectcache.NewObjectCache(config.ObjectCache),
+	}
 	toReturn.Zones = make([]*Zone, len(toReturn.Config.Zones))
 	for i, z := range toReturn.Config.Zones {
-		toReturn.Zones[i] = NewZone(z, config.LogLevel)
+		toReturn.Zones[i] = NewZone(z, config.Listen, toReturn.GlobalLimits, toReturn.BandwidthBuckets, toReturn.ObjectCache, config.LogLevel)
 	}
+	go toReturn.runLimitStateCleaner()
 	return toReturn
 }
 
 type CDN struct {
-	Config conf.ConfigYaml
-	Zones  []*Zone
+	Config           conf.ConfigYaml
+	Zones            []*Zone
+	GlobalLimits     *limits.GlobalLimits
+	BandwidthBuckets *limits.BandwidthBuckets
+	ObjectCache      *objectcache.ObjectCache
+}
+
+func (cdn *CDN) Close() {
+	for _, z := range cdn.Zones {
+		if z != nil {
+			err := z.saveAccessLimitState()
+			if err != nil {
+				utils.LogPrintln(1, "Access Limit State Save Error: "+err.Error())
+			}
+		}
+	}
+	if cdn.ObjectCache != nil {
+		hits, misses, size, entries := cdn.ObjectCache.Stats()
+		utils.LogPrintln(1, "Object Cache: "+strconv.FormatUint(hits, 10)+" hits, "+strconv.FormatUint(misses, 10)+" misses, "+strconv.Itoa(entries)+" entries, "+strconv.FormatInt(size, 10)+" bytes")
+	}
+}
+
+func (cdn *CDN) runLimitStateCleaner() {
+	idleTimeout := cdn.Config.LimitState.GetIdleTimeout()
+	maxEntries := cdn.Config.LimitState.MaxEntries
+	for range time.Tick(cdn.Config.LimitState.GetCleanInterval()) {
+		for _, z := range cdn.Zones {
+			if z != nil {
+				z.cleanLimitState(idleTimeout, maxEntries)
+			}
+		}
+		cdn.BandwidthBuckets.Clean(idleTimeout, maxEntries)
+	}
 }
@@ -0,0 +1,79 @@
+package cdn
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+)
+
+func newCompressionTestZone(t *testing.T) *Zone {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello hello hello hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	zone := NewZone(conf.ZoneYaml{
+		Name:            "z",
+		Backend:         "filesystem",
+		BackendSettings: map[string]string{"directoryPath": dir},
+		Compression:     conf.CompressionYaml{Enabled: true, Encodings: []string{"gzip"}},
+	}, conf.ListenYaml{}, limits.NewGlobalLimits(conf.GlobalLimitsYaml{}), limits.NewBandwidthBuckets(), nil, 0)
+	if zone == nil {
+		t.Fatal("failed to create the zone")
+	}
+	return zone
+}
+
+func TestZoneCompression(t *testing.T) {
+	zone := newCompressionTestZone(t)
+	req := httptest.NewRequest(http.MethodGet, "/z/a.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	zone.ZoneHandleRequest(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip response, got %d %v", rec.Code, rec.Header())
+	}
+	gzReader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := io.ReadAll(gzReader); err != nil || string(body) != "hello hello hello hello" {
+		t.Fatalf("unexpected body %q: %v", body, err)
+	}
+}
+
+func TestZoneCompressionEncoderFailure(t *testing.T) {
+	zone := newCompressionTestZone(t)
+	oldEncodingWriter := newEncodingWriter
+	newEncodingWriter = func(encoding string, w io.Writer) (io.WriteCloser, error) {
+		return nil, errors.New("encoder unavailable")
+	}
+	defer func() { newEncodingWriter = oldEncodingWriter }()
+
+	identityReq := httptest.NewRequest(http.MethodGet, "/z/a.txt", nil)
+	identityRec := httptest.NewRecorder()
+	zone.ZoneHandleRequest(identityRec, identityReq)
+
+	req := httptest.NewRequest(http.MethodGet, "/z/a.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	zone.ZoneHandleRequest(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Vary") != "" {
+		t.Fatalf("expected no encoding headers, got %v", rec.Header())
+	}
+	if rec.Header().Get("ETag") != identityRec.Header().Get("ETag") || rec.Header().Get("Content-Length") != "23" {
+		t.Fatalf("expected the identity ETag and length, got %v", rec.Header())
+	}
+	if rec.Body.String() != "hello hello hello hello" {
+		t.Fatalf("expected the identity body, got %q", rec.Body.String())
+	}
+}
@@ -2,6 +2,7 @@ package limits
 
 import (
 	"snow.mrmelon54.xyz/snowedin/conf"
+	"sync"
 	"time"
 )
 
@@ -15,14 +16,66 @@ func NewAccessLimit(conf conf.AccessLimitYaml) *AccessLimit {
 		Gone:              false,
 		AccessLimit:       conf.AccessLimit != 0,
 		AccessesRemaining: conf.AccessLimit,
+		accessesInitial:   conf.AccessLimit,
+		LastUsed:          time.Now(),
+		mu:                &sync.Mutex{},
 	}
 }
 
+func NewAccessLimitFromState(conf conf.AccessLimitYaml, state AccessLimitState) *AccessLimit {
+	toReturn := NewAccessLimit(conf)
+	toReturn.ExpireTime = state.ExpireTime
+	toReturn.Gone = state.Gone
+	if toReturn.AccessLimit {
+		toReturn.AccessesRemaining = state.AccessesRemaining
+	}
+	return toReturn
+}
+
+type AccessLimitKey struct {
+	Path   string `yaml:"path"`
+	Scope  string `yaml:"scope"`
+	Client string `yaml:"client,omitempty"`
+}
+
+type AccessLimitState struct {
+	AccessesRemaining uint      `yaml:"accessesRemaining"`
+	ExpireTime        time.Time `yaml:"expireTime"`
+	Gone              bool      `yaml:"gone"`
+}
+
 type AccessLimit struct {
+	StateUsers
+	mu                *sync.Mutex
 	ExpireTime        time.Time
 	Gone              bool
 	AccessLimit       bool
 	AccessesRemaining uint
+	accessesInitial   uint
+	LastUsed          time.Time
+}
+
+func (al *AccessLimit) Active() bool {
+	if al == nil {
+		return false
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.Gone || !al.ExpireTime.IsZero() || (al.AccessLimit && al.AccessesRemaining != al.accessesInitial)
+}
+
+func (al *AccessLimit) InUse() bool {
+	if al == nil {
+		return false
+	}
+	return al.StateUsers.InUse()
+}
+
+func (al *AccessLimit) GetLastUsed() time.Time {
+	if al == nil {
+		return time.Time{}
+	}
+	return al.LastUsed
 }
 
 func (al *AccessLimit) Expired() bool {
@@ -38,6 +91,8 @@ func (al *AccessLimit) Expired() bool {
 }
 
 func (al *AccessLimit) AccessLimitReached() bool {
+	al.mu.Lock()
+	defer al.mu.Unlock()
 	if al.AccessLimit {
 		if al.AccessesRemaining == 0 {
 			return true
@@ -47,3 +102,13 @@ func (al *AccessLimit) AccessLimitReached() bool {
 	}
 	return false
 }
+
+func (al *AccessLimit) GetState() AccessLimitState {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return AccessLimitState{
+		AccessesRemaining: al.AccessesRemaining,
+		ExpireTime:        al.ExpireTime,
+		Gone:              al.Gone,
+	}
+}
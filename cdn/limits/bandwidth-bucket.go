@@ -0,0 +1,72 @@
+package limits
+
+import (
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"sync"
+	"time"
+)
+
+func NewBandwidthBucket(conf conf.BandwidthLimitYaml) *BandwidthBucket {
+	return &BandwidthBucket{
+		bytesPerSecond: float64(conf.Bytes) / conf.Interval.Seconds(),
+		burst:          int64(conf.GetBurst()),
+		tokens:         float64(conf.GetBurst()),
+		lastTake:       time.Now(),
+		LimitConf:      conf,
+		LastUsed:       time.Now(),
+		mu:             &sync.Mutex{},
+	}
+}
+
+type BandwidthBucket struct {
+	StateUsers
+	mu             *sync.Mutex
+	bytesPerSecond float64
+	burst          int64
+	tokens         float64
+	lastTake       time.Time
+	LimitConf      conf.BandwidthLimitYaml
+	LastUsed       time.Time
+}
+
+func (bb *BandwidthBucket) Active() bool {
+	if bb == nil {
+		return false
+	}
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	return bb.tokens+time.Since(bb.lastTake).Seconds()*bb.bytesPerSecond < float64(bb.burst)
+}
+
+func (bb *BandwidthBucket) InUse() bool {
+	if bb == nil {
+		return false
+	}
+	return bb.StateUsers.InUse()
+}
+
+func (bb *BandwidthBucket) GetLastUsed() time.Time {
+	if bb == nil {
+		return time.Time{}
+	}
+	return bb.LastUsed
+}
+
+func (bb *BandwidthBucket) Take(n int64) {
+	bb.mu.Lock()
+	now := time.Now()
+	bb.tokens += now.Sub(bb.lastTake).Seconds() * bb.bytesPerSecond
+	if bb.tokens > float64(bb.burst) {
+		bb.tokens = float64(bb.burst)
+	}
+	bb.lastTake = now
+	bb.tokens -= float64(n)
+	var wait time.Duration
+	if bb.tokens < 0 {
+		wait = time.Duration(-bb.tokens / bb.bytesPerSecond * float64(time.Second))
+	}
+	bb.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
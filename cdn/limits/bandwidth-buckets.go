@@ -0,0 +1,39 @@
+package limits
+
+import (
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"sync"
+	"time"
+)
+
+func NewBandwidthBuckets() *BandwidthBuckets {
+	return &BandwidthBuckets{
+		Buckets: make(map[string]*BandwidthBucket),
+		mu:      &sync.Mutex{},
+	}
+}
+
+type BandwidthBuckets struct {
+	mu      *sync.Mutex
+	Buckets map[string]*BandwidthBucket
+}
+
+func (bbs *BandwidthBuckets) GetBucket(clientIP string, bly conf.BandwidthLimitYaml) *BandwidthBucket {
+	key := bly.GetBucketKey(clientIP)
+	bbs.mu.Lock()
+	defer bbs.mu.Unlock()
+	a := bbs.Buckets[key]
+	if a == nil {
+		a = NewBandwidthBucket(bly)
+		bbs.Buckets[key] = a
+	}
+	a.LastUsed = time.Now()
+	a.Acquire()
+	return a
+}
+
+func (bbs *BandwidthBuckets) Clean(idleTimeout time.Duration, maxEntries int) {
+	bbs.mu.Lock()
+	CleanStateMap(bbs.Buckets, idleTimeout, maxEntries)
+	bbs.mu.Unlock()
+}
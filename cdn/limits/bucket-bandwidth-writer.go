@@ -0,0 +1,31 @@
+package limits
+
+import "io"
+
+func NewBucketBandwidthWriter(bucket *BandwidthBucket, targetWriter io.Writer) io.Writer {
+	return &BucketBandwidthWriter{
+		passedWriter: targetWriter,
+		bucket:       bucket,
+	}
+}
+
+type BucketBandwidthWriter struct {
+	passedWriter io.Writer
+	bucket       *BandwidthBucket
+}
+
+func (bbw *BucketBandwidthWriter) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		chunk := p[n:]
+		if int64(len(chunk)) > bbw.bucket.burst {
+			chunk = chunk[:bbw.bucket.burst]
+		}
+		bbw.bucket.Take(int64(len(chunk)))
+		written, err := bbw.passedWriter.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
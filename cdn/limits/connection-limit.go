@@ -3,20 +3,49 @@ package limits
 import (
 	"snow.mrmelon54.xyz/snowedin/conf"
 	"sync"
+	"time"
 )
 
 func NewConnectionLimit(conf conf.LimitConnectionYaml) *ConnectionLimit {
 	return &ConnectionLimit{
 		ConnectionsRemaining: conf.MaxConnections,
 		LimitConf:            conf,
+		LastUsed:             time.Now(),
 		mu:                   &sync.Mutex{},
 	}
 }
 
 type ConnectionLimit struct {
+	StateUsers
 	mu                   *sync.Mutex
 	ConnectionsRemaining uint
 	LimitConf            conf.LimitConnectionYaml
+	LastUsed             time.Time
+	finishedConnections  uint64
+	averageDuration      time.Duration
+}
+
+func (cl *ConnectionLimit) Active() bool {
+	if cl == nil {
+		return false
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.ConnectionsRemaining != cl.LimitConf.MaxConnections
+}
+
+func (cl *ConnectionLimit) InUse() bool {
+	if cl == nil {
+		return false
+	}
+	return cl.StateUsers.InUse()
+}
+
+func (cl *ConnectionLimit) GetLastUsed() time.Time {
+	if cl == nil {
+		return time.Time{}
+	}
+	return cl.LastUsed
 }
 
 func (cl *ConnectionLimit) StartConnection() bool {
@@ -35,3 +64,28 @@ func (cl *ConnectionLimit) StopConnection() {
 	cl.ConnectionsRemaining++
 	cl.mu.Unlock()
 }
+
+func (cl *ConnectionLimit) FinishConnection(started time.Time) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.ConnectionsRemaining++
+	duration := time.Since(started)
+	if cl.finishedConnections == 0 {
+		cl.averageDuration = duration
+	} else {
+		cl.averageDuration += (duration - cl.averageDuration) / 5
+	}
+	cl.finishedConnections++
+}
+
+func (cl *ConnectionLimit) RetryAfter(fallback time.Duration) time.Duration {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.ConnectionsRemaining > 0 {
+		return 0
+	}
+	if cl.finishedConnections == 0 {
+		return fallback
+	}
+	return cl.averageDuration
+}
@@ -0,0 +1,47 @@
+package limits
+
+import (
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+	"time"
+)
+
+func TestConnectionLimitRetryAfter(t *testing.T) {
+	cl := NewConnectionLimit(conf.LimitConnectionYaml{MaxConnections: 1})
+	if retryAfter := cl.RetryAfter(time.Second); retryAfter != 0 {
+		t.Fatalf("expected no retry delay with a free connection, got %v", retryAfter)
+	}
+
+	if !cl.StartConnection() || cl.StartConnection() {
+		t.Fatal("expected exactly one connection to start")
+	}
+	if retryAfter := cl.RetryAfter(time.Second); retryAfter != time.Second {
+		t.Fatalf("expected the fallback before any connection finished, got %v", retryAfter)
+	}
+
+	cl.FinishConnection(time.Now().Add(-10 * time.Second))
+	if !cl.StartConnection() {
+		t.Fatal("expected the finished connection to be released")
+	}
+	if retryAfter := cl.RetryAfter(time.Second); retryAfter < 10*time.Second || retryAfter > 11*time.Second {
+		t.Fatalf("expected the average connection duration, got %v", retryAfter)
+	}
+
+	cl.FinishConnection(time.Now().Add(-5 * time.Second))
+	if !cl.StartConnection() {
+		t.Fatal("expected the finished connection to be released")
+	}
+	if retryAfter := cl.RetryAfter(time.Second); retryAfter < 9*time.Second || retryAfter > 10*time.Second {
+		t.Fatalf("expected the average to move towards the newest duration, got %v", retryAfter)
+	}
+}
+
+func TestConnectionLimitStopDoesNotRecordDuration(t *testing.T) {
+	cl := NewConnectionLimit(conf.LimitConnectionYaml{MaxConnections: 1})
+	cl.StartConnection()
+	cl.StopConnection()
+	cl.StartConnection()
+	if retryAfter := cl.RetryAfter(2 * time.Second); retryAfter != 2*time.Second {
+		t.Fatalf("expected the fallback when no connection finished, got %v", retryAfter)
+	}
+}
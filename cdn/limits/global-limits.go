@@ -0,0 +1,51 @@
+package limits
+
+import (
+	"io"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"time"
+)
+
+func NewGlobalLimits(conf conf.GlobalLimitsYaml) *GlobalLimits {
+	toReturn := &GlobalLimits{
+		Connection: NewConnectionLimit(conf.GetLimitConnectionYaml()),
+		Request:    NewRequestLimit(conf.GetLimitRequestsYaml()),
+	}
+	if conf.GetBandwidthLimitYaml().YamlValid() {
+		toReturn.Bandwidth = NewBandwidthBucket(conf.GetBandwidthLimitYaml())
+	}
+	return toReturn
+}
+
+type GlobalLimits struct {
+	Connection *ConnectionLimit
+	Request    *RequestLimit
+	Bandwidth  *BandwidthBucket
+}
+
+func (gl *GlobalLimits) StartConnection() bool {
+	return !gl.Connection.LimitConf.YamlValid() || gl.Connection.StartConnection()
+}
+
+func (gl *GlobalLimits) StopConnection() {
+	if gl.Connection.LimitConf.YamlValid() {
+		gl.Connection.StopConnection()
+	}
+}
+
+func (gl *GlobalLimits) FinishConnection(started time.Time) {
+	if gl.Connection.LimitConf.YamlValid() {
+		gl.Connection.FinishConnection(started)
+	}
+}
+
+func (gl *GlobalLimits) StartRequest() bool {
+	return !gl.Request.LimitConf.YamlValid() || gl.Request.StartRequest()
+}
+
+func (gl *GlobalLimits) GetBandwidthWriter(targetWriter io.Writer) io.Writer {
+	if gl.Bandwidth == nil {
+		return targetWriter
+	}
+	return NewBucketBandwidthWriter(gl.Bandwidth, targetWriter)
+}
@@ -1,6 +1,7 @@
 package limits
 
 import (
+	"math"
 	"snow.mrmelon54.xyz/snowedin/conf"
 	"sync"
 	"time"
@@ -11,29 +12,197 @@ func NewRequestLimit(conf conf.LimitRequestsYaml) *RequestLimit {
 		ExpireTime:        time.Now().Add(conf.RequestRateInterval),
 		RequestsRemaining: conf.MaxRequests,
 		LimitConf:         conf,
+		LastUsed:          time.Now(),
+		tokens:            float64(conf.GetBurst()),
+		lastRefill:        time.Now(),
 		mu:                &sync.Mutex{},
 	}
 }
 
 type RequestLimit struct {
+	StateUsers
 	mu                *sync.Mutex
 	ExpireTime        time.Time
 	RequestsRemaining uint
 	LimitConf         conf.LimitRequestsYaml
+	LastUsed          time.Time
+	previousRequests  uint
+	currentRequests   uint
+	tokens            float64
+	lastRefill        time.Time
 }
 
-func (rl *RequestLimit) StartRequest() bool {
+func (rl *RequestLimit) Active() bool {
+	if rl == nil {
+		return false
+	}
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
-	if rl.ExpireTime.After(time.Now()) {
-		if rl.RequestsRemaining == 0 {
+	if !rl.LimitConf.YamlValid() {
+		return false
+	}
+	switch rl.LimitConf.GetMode() {
+	case conf.RequestLimitModeSlidingWindow:
+		return rl.ExpireTime.Add(rl.LimitConf.RequestRateInterval).After(time.Now())
+	case conf.RequestLimitModeTokenBucket:
+		rl.refill(time.Now())
+		return rl.tokens < float64(rl.LimitConf.GetBurst())
+	default:
+		return rl.ExpireTime.After(time.Now())
+	}
+}
+
+func (rl *RequestLimit) InUse() bool {
+	if rl == nil {
+		return false
+	}
+	return rl.StateUsers.InUse()
+}
+
+func (rl *RequestLimit) GetLastUsed() time.Time {
+	if rl == nil {
+		return time.Time{}
+	}
+	return rl.LastUsed
+}
+
+func (rl *RequestLimit) StartRequest() bool {
+	return StartRequests(rl)
+}
+
+func StartRequests(requestLimits ...*RequestLimit) bool {
+	active := make([]*RequestLimit, 0, len(requestLimits))
+	for _, rl := range requestLimits {
+		if rl != nil && rl.LimitConf.YamlValid() {
+			active = append(active, rl)
+		}
+	}
+	for _, rl := range active {
+		rl.mu.Lock()
+	}
+	defer func() {
+		for _, rl := range active {
+			rl.mu.Unlock()
+		}
+	}()
+	now := time.Now()
+	for _, rl := range active {
+		if !rl.canStartRequest(now) {
 			return false
-		} else {
-			rl.RequestsRemaining--
 		}
-	} else {
-		rl.ExpireTime = time.Now().Add(rl.LimitConf.RequestRateInterval)
-		rl.RequestsRemaining = rl.LimitConf.MaxRequests - 1
+	}
+	for _, rl := range active {
+		rl.consumeRequest()
 	}
 	return true
 }
+
+func (rl *RequestLimit) canStartRequest(now time.Time) bool {
+	switch rl.LimitConf.GetMode() {
+	case conf.RequestLimitModeSlidingWindow:
+		interval := rl.LimitConf.RequestRateInterval
+		if !rl.ExpireTime.After(now) {
+			windowStart := rl.ExpireTime.Add(-interval)
+			elapsedWindows := now.Sub(windowStart) / interval
+			if elapsedWindows == 1 {
+				rl.previousRequests = rl.currentRequests
+			} else {
+				rl.previousRequests = 0
+			}
+			rl.currentRequests = 0
+			rl.ExpireTime = windowStart.Add((elapsedWindows + 1) * interval)
+		}
+		previousWeight := float64(rl.ExpireTime.Sub(now)) / float64(interval)
+		return float64(rl.previousRequests)*previousWeight+float64(rl.currentRequests) < float64(rl.LimitConf.MaxRequests)
+	case conf.RequestLimitModeTokenBucket:
+		rl.refill(now)
+		return rl.tokens >= 1
+	default:
+		if !rl.ExpireTime.After(now) {
+			rl.ExpireTime = now.Add(rl.LimitConf.RequestRateInterval)
+			rl.RequestsRemaining = rl.LimitConf.MaxRequests
+		}
+		return rl.RequestsRemaining > 0
+	}
+}
+
+func (rl *RequestLimit) consumeRequest() {
+	switch rl.LimitConf.GetMode() {
+	case conf.RequestLimitModeSlidingWindow:
+		rl.currentRequests++
+	case conf.RequestLimitModeTokenBucket:
+		rl.tokens--
+	default:
+		rl.RequestsRemaining--
+	}
+}
+
+func (rl *RequestLimit) refill(now time.Time) {
+	rl.tokens = math.Min(float64(rl.LimitConf.GetBurst()), rl.tokens+now.Sub(rl.lastRefill).Seconds()*rl.LimitConf.GetRefillRate())
+	rl.lastRefill = now
+}
+
+func (rl *RequestLimit) Status() (limit uint, remaining uint, reset time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	switch rl.LimitConf.GetMode() {
+	case conf.RequestLimitModeSlidingWindow:
+		limit = rl.LimitConf.MaxRequests
+		used := float64(rl.currentRequests)
+		if rl.ExpireTime.After(now) {
+			used += float64(rl.previousRequests) * float64(rl.ExpireTime.Sub(now)) / float64(rl.LimitConf.RequestRateInterval)
+		} else {
+			return limit, limit, 0
+		}
+		if used < float64(limit) {
+			remaining = limit - uint(math.Ceil(used))
+		}
+		return limit, remaining, rl.ExpireTime.Sub(now)
+	case conf.RequestLimitModeTokenBucket:
+		rl.refill(now)
+		limit = rl.LimitConf.GetBurst()
+		remaining = uint(rl.tokens)
+		reset = time.Duration((float64(limit) - rl.tokens) / rl.LimitConf.GetRefillRate() * float64(time.Second))
+		return limit, remaining, reset
+	default:
+		if rl.ExpireTime.After(now) {
+			return rl.LimitConf.MaxRequests, rl.RequestsRemaining, rl.ExpireTime.Sub(now)
+		}
+		return rl.LimitConf.MaxRequests, rl.LimitConf.MaxRequests, 0
+	}
+}
+
+func (rl *RequestLimit) RetryAfter() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	if rl.LimitConf.GetMode() == conf.RequestLimitModeTokenBucket {
+		rl.refill(now)
+		if rl.tokens >= 1 {
+			return 0
+		}
+		return time.Duration((1 - rl.tokens) / rl.LimitConf.GetRefillRate() * float64(time.Second))
+	}
+	if !rl.ExpireTime.After(now) {
+		return 0
+	}
+	interval := float64(rl.LimitConf.RequestRateInterval)
+	maxRequests := float64(rl.LimitConf.MaxRequests)
+	if rl.LimitConf.GetMode() == conf.RequestLimitModeSlidingWindow {
+		var retryTime time.Time
+		if float64(rl.currentRequests) >= maxRequests {
+			retryTime = rl.ExpireTime.Add(time.Duration(interval * (1 - maxRequests/float64(rl.currentRequests))))
+		} else if rl.previousRequests > 0 {
+			retryTime = rl.ExpireTime.Add(-time.Duration(interval * (maxRequests - float64(rl.currentRequests)) / float64(rl.previousRequests)))
+		}
+		if retryTime.After(now) {
+			return retryTime.Sub(now)
+		}
+		return 0
+	}
+	if rl.RequestsRemaining == 0 {
+		return rl.ExpireTime.Sub(now)
+	}
+	return 0
+}
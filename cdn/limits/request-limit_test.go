@@ -0,0 +1,52 @@
+package limits
+
+import (
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+	"time"
+)
+
+func TestStartRequestsIsAllOrNothing(t *testing.T) {
+	modes := []conf.LimitRequestsYaml{
+		{Mode: conf.RequestLimitModeFixedWindow, MaxRequests: 5, RequestRateInterval: time.Hour},
+		{Mode: conf.RequestLimitModeSlidingWindow, MaxRequests: 5, RequestRateInterval: time.Hour},
+		{Mode: conf.RequestLimitModeTokenBucket, Burst: 5, RefillRate: 0.0001},
+	}
+	for _, mode := range modes {
+		t.Run(mode.Mode, func(t *testing.T) {
+			clientLimit := NewRequestLimit(mode)
+			zoneConf := mode
+			zoneConf.MaxRequests, zoneConf.Burst = 2, 2
+			zoneLimit := NewRequestLimit(zoneConf)
+			cdnLimit := NewRequestLimit(conf.LimitRequestsYaml{})
+
+			for i := 0; i < 2; i++ {
+				if !StartRequests(clientLimit, zoneLimit, cdnLimit) {
+					t.Fatalf("request %d should be allowed", i+1)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if StartRequests(clientLimit, zoneLimit, cdnLimit) {
+					t.Fatal("request should be rejected by the zone limit")
+				}
+			}
+			if _, remaining, _ := clientLimit.Status(); remaining != 3 {
+				t.Fatalf("rejected requests consumed the client limit, %d remaining", remaining)
+			}
+			for i := 0; i < 3; i++ {
+				if !StartRequests(clientLimit) {
+					t.Fatalf("client request %d should be allowed", i+1)
+				}
+			}
+			if StartRequests(clientLimit) {
+				t.Fatal("client limit should be exhausted")
+			}
+		})
+	}
+}
+
+func TestStartRequestsSkipsInactiveLimits(t *testing.T) {
+	if !StartRequests(nil, NewRequestLimit(conf.LimitRequestsYaml{})) {
+		t.Fatal("requests without active limits should be allowed")
+	}
+}
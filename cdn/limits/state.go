@@ -0,0 +1,65 @@
+package limits
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type State interface {
+	Active() bool
+	InUse() bool
+	GetLastUsed() time.Time
+}
+
+type StateUsers struct {
+	users int32
+}
+
+func (su *StateUsers) Acquire() {
+	atomic.AddInt32(&su.users, 1)
+}
+
+func (su *StateUsers) Release() {
+	atomic.AddInt32(&su.users, -1)
+}
+
+func (su *StateUsers) InUse() bool {
+	return atomic.LoadInt32(&su.users) > 0
+}
+
+func CleanStateMap[K comparable, T State](stateMap map[K]T, idleTimeout time.Duration, maxEntries int) {
+	CleanStateMapFunc(stateMap, idleTimeout, maxEntries, nil)
+}
+
+func CleanStateMapFunc[K comparable, T State](stateMap map[K]T, idleTimeout time.Duration, maxEntries int, onRemove func(key K)) {
+	remove := func(k K) {
+		delete(stateMap, k)
+		if onRemove != nil {
+			onRemove(k)
+		}
+	}
+	now := time.Now()
+	var inactive []K
+	for k, v := range stateMap {
+		if v.Active() || v.InUse() {
+			continue
+		}
+		if now.Sub(v.GetLastUsed()) > idleTimeout {
+			remove(k)
+		} else {
+			inactive = append(inactive, k)
+		}
+	}
+	if maxEntries > 0 && len(stateMap) > maxEntries {
+		sort.Slice(inactive, func(i, j int) bool {
+			return stateMap[inactive[i]].GetLastUsed().Before(stateMap[inactive[j]].GetLastUsed())
+		})
+		for _, k := range inactive {
+			if len(stateMap) <= maxEntries {
+				break
+			}
+			remove(k)
+		}
+	}
+}
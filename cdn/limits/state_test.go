@@ -0,0 +1,93 @@
+package limits
+
+import (
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+	"time"
+)
+
+func TestCleanStateMapKeepsEntriesInUse(t *testing.T) {
+	stateMap := make(map[string]*RequestLimit)
+	for _, k := range []string{"a", "b", "c"} {
+		stateMap[k] = NewRequestLimit(conf.LimitRequestsYaml{})
+		stateMap[k].LastUsed = time.Now().Add(-time.Hour)
+	}
+	stateMap["b"].Acquire()
+
+	CleanStateMap(stateMap, time.Minute, 0)
+	if len(stateMap) != 1 || stateMap["b"] == nil {
+		t.Fatalf("expected only the entry in use to be kept, got %v", stateMap)
+	}
+
+	stateMap["b"].Release()
+	CleanStateMap(stateMap, time.Minute, 0)
+	if len(stateMap) != 0 {
+		t.Fatalf("expected the released entry to be removed, got %v", stateMap)
+	}
+}
+
+func TestCleanStateMapMaxEntriesKeepsEntriesInUse(t *testing.T) {
+	stateMap := make(map[string]*ConnectionLimit)
+	for i, k := range []string{"a", "b", "c", "d"} {
+		stateMap[k] = NewConnectionLimit(conf.LimitConnectionYaml{})
+		stateMap[k].LastUsed = time.Now().Add(time.Duration(i) * time.Second)
+	}
+	stateMap["a"].Acquire()
+
+	CleanStateMap(stateMap, time.Hour, 2)
+	if len(stateMap) != 2 || stateMap["a"] == nil || stateMap["d"] == nil {
+		t.Fatalf("expected the entry in use and the newest entry to be kept, got %v", stateMap)
+	}
+}
+
+func TestAccessLimitActive(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  func() *AccessLimit
+		active bool
+	}{
+		{"nil", func() *AccessLimit { return nil }, false},
+		{"unused", func() *AccessLimit { return NewAccessLimit(conf.AccessLimitYaml{AccessLimit: 3}) }, false},
+		{"accessed", func() *AccessLimit {
+			al := NewAccessLimit(conf.AccessLimitYaml{AccessLimit: 3})
+			al.AccessLimitReached()
+			return al
+		}, true},
+		{"expiring", func() *AccessLimit { return NewAccessLimit(conf.AccessLimitYaml{ExpireTime: time.Hour}) }, true},
+		{"expired", func() *AccessLimit {
+			al := NewAccessLimit(conf.AccessLimitYaml{ExpireTime: time.Hour})
+			al.ExpireTime = time.Now().Add(-time.Second)
+			return al
+		}, true},
+		{"gone", func() *AccessLimit {
+			al := NewAccessLimit(conf.AccessLimitYaml{})
+			al.Gone = true
+			return al
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if active := tt.limit().Active(); active != tt.active {
+				t.Fatalf("expected %v, got %v", tt.active, active)
+			}
+		})
+	}
+}
+
+func TestCleanStateMapKeepsExpiredAccessLimits(t *testing.T) {
+	stateMap := make(map[string]*AccessLimit)
+	stateMap["expired"] = NewAccessLimit(conf.AccessLimitYaml{ExpireTime: time.Hour})
+	stateMap["expired"].ExpireTime = time.Now().Add(-time.Second)
+	stateMap["expired"].LastUsed = time.Now().Add(-time.Hour)
+	stateMap["used"] = NewAccessLimit(conf.AccessLimitYaml{AccessLimit: 1})
+	stateMap["used"].AccessLimitReached()
+	stateMap["used"].LastUsed = time.Now().Add(-time.Hour)
+
+	CleanStateMap(stateMap, time.Minute, 1)
+	if stateMap["expired"] == nil || !stateMap["expired"].Expired() {
+		t.Fatal("expected the expired access limit to survive the janitor and stay expired")
+	}
+	if stateMap["used"] == nil || !stateMap["used"].AccessLimitReached() {
+		t.Fatal("expected the used up access limit to survive the janitor and stay reached")
+	}
+}
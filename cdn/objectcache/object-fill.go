@@ -0,0 +1,67 @@
+package objectcache
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+func newObjectFill(size int64, modified time.Time) *ObjectFill {
+	mu := &sync.Mutex{}
+	return &ObjectFill{
+		mu:         mu,
+		cond:       sync.NewCond(mu),
+		size:       size,
+		modifyTime: modified,
+		data:       make([]byte, 0, size),
+	}
+}
+
+type ObjectFill struct {
+	mu          *sync.Mutex
+	cond        *sync.Cond
+	size        int64
+	modifyTime  time.Time
+	data        []byte
+	done        bool
+	ok          bool
+	invalidated bool
+}
+
+func (of *ObjectFill) Write(p []byte) (n int, err error) {
+	of.mu.Lock()
+	of.data = append(of.data, p...)
+	of.mu.Unlock()
+	of.cond.Broadcast()
+	return len(p), nil
+}
+
+func (of *ObjectFill) StreamTo(w io.Writer) (n int64, ok bool, err error) {
+	for {
+		of.mu.Lock()
+		for int64(len(of.data)) == n && !of.done {
+			of.cond.Wait()
+		}
+		chunk := of.data[n:]
+		done, fillOk := of.done, of.ok
+		of.mu.Unlock()
+		if len(chunk) == 0 && done {
+			return n, fillOk, nil
+		}
+		written, err := w.Write(chunk)
+		n += int64(written)
+		if err != nil {
+			return n, false, err
+		}
+	}
+}
+
+func (of *ObjectFill) finish(ok bool) ([]byte, bool) {
+	of.mu.Lock()
+	of.done = true
+	of.ok = ok && int64(len(of.data)) == of.size
+	data, ok := of.data, of.ok
+	of.mu.Unlock()
+	of.cond.Broadcast()
+	return data, ok
+}
@@ -0,0 +1,194 @@
+package objectcache
+
+import (
+	"container/list"
+	"io"
+	pth "path"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"sync"
+	"time"
+)
+
+func NewObjectCache(conf conf.ObjectCacheYaml) *ObjectCache {
+	if !conf.YamlValid() {
+		return nil
+	}
+	return &ObjectCache{
+		maxSize:       conf.MaxSize,
+		maxObjectSize: conf.GetMaxObjectSize(),
+		entries:       make(map[Key]*list.Element),
+		entryDirs:     make(keyIndex),
+		fills:         make(map[Key]*ObjectFill),
+		fillDirs:      make(keyIndex),
+		lru:           list.New(),
+		mu:            &sync.Mutex{},
+	}
+}
+
+type ObjectCache struct {
+	mu            *sync.Mutex
+	maxSize       int64
+	maxObjectSize int64
+	size          int64
+	entries       map[Key]*list.Element
+	entryDirs     keyIndex
+	fills         map[Key]*ObjectFill
+	fillDirs      keyIndex
+	lru           *list.List
+	hits          uint64
+	misses        uint64
+}
+
+type Key struct {
+	Zone string
+	Path string
+}
+
+type cacheEntry struct {
+	key        Key
+	data       []byte
+	modifyTime time.Time
+}
+
+func (oc *ObjectCache) Cacheable(size int64) bool {
+	return size >= 0 && size <= oc.maxObjectSize
+}
+
+func (oc *ObjectCache) Get(key Key, size int64, modified time.Time) ([]byte, bool) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if elem, ok := oc.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if int64(len(entry.data)) == size && entry.modifyTime.Equal(modified) {
+			oc.lru.MoveToFront(elem)
+			oc.hits++
+			return entry.data, true
+		}
+		oc.removeElement(elem)
+	}
+	oc.misses++
+	return nil, false
+}
+
+func (oc *ObjectCache) StartFill(key Key, size int64, modified time.Time, fillData func(w io.Writer) error) *ObjectFill {
+	oc.mu.Lock()
+	if fill := oc.fills[key]; fill != nil && fill.size == size && fill.modifyTime.Equal(modified) {
+		oc.mu.Unlock()
+		return fill
+	}
+	fill := newObjectFill(size, modified)
+	oc.fills[key] = fill
+	oc.fillDirs.add(key)
+	oc.mu.Unlock()
+
+	go func() {
+		err := fillData(fill)
+		data, ok := fill.finish(err == nil)
+		oc.mu.Lock()
+		if oc.fills[key] == fill {
+			delete(oc.fills, key)
+			oc.fillDirs.remove(key)
+		}
+		oc.mu.Unlock()
+		if ok {
+			oc.put(key, fill, data, modified)
+		}
+	}()
+	return fill
+}
+
+func (oc *ObjectCache) Put(key Key, data []byte, modified time.Time) {
+	oc.put(key, nil, data, modified)
+}
+
+func (oc *ObjectCache) put(key Key, fill *ObjectFill, data []byte, modified time.Time) {
+	if !oc.Cacheable(int64(len(data))) {
+		return
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if fill != nil && fill.invalidated {
+		return
+	}
+	if elem, ok := oc.entries[key]; ok {
+		oc.removeElement(elem)
+	}
+	oc.entries[key] = oc.lru.PushFront(&cacheEntry{
+		key:        key,
+		data:       data,
+		modifyTime: modified,
+	})
+	oc.entryDirs.add(key)
+	oc.size += int64(len(data))
+	for oc.size > oc.maxSize {
+		oc.removeElement(oc.lru.Back())
+	}
+}
+
+func (oc *ObjectCache) Invalidate(key Key) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if elem, ok := oc.entries[key]; ok {
+		oc.removeElement(elem)
+	}
+	for k := range oc.entryDirs[key] {
+		oc.removeElement(oc.entries[k])
+	}
+	oc.invalidateFill(key)
+	for k := range oc.fillDirs[key] {
+		oc.invalidateFill(k)
+	}
+}
+
+func (oc *ObjectCache) Stats() (hits uint64, misses uint64, size int64, entries int) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	return oc.hits, oc.misses, oc.size, len(oc.entries)
+}
+
+func (oc *ObjectCache) invalidateFill(key Key) {
+	if fill := oc.fills[key]; fill != nil {
+		fill.invalidated = true
+		delete(oc.fills, key)
+		oc.fillDirs.remove(key)
+	}
+}
+
+func (oc *ObjectCache) removeElement(elem *list.Element) {
+	entry := oc.lru.Remove(elem).(*cacheEntry)
+	delete(oc.entries, entry.key)
+	oc.entryDirs.remove(entry.key)
+	oc.size -= int64(len(entry.data))
+}
+
+type keyIndex map[Key]map[Key]struct{}
+
+func (ki keyIndex) add(key Key) {
+	for dir := key.Path; dir != ""; {
+		dir = parentPath(dir)
+		dirKey := Key{Zone: key.Zone, Path: dir}
+		if ki[dirKey] == nil {
+			ki[dirKey] = make(map[Key]struct{})
+		}
+		ki[dirKey][key] = struct{}{}
+	}
+}
+
+func (ki keyIndex) remove(key Key) {
+	for dir := key.Path; dir != ""; {
+		dir = parentPath(dir)
+		dirKey := Key{Zone: key.Zone, Path: dir}
+		delete(ki[dirKey], key)
+		if len(ki[dirKey]) == 0 {
+			delete(ki, dirKey)
+		}
+	}
+}
+
+func parentPath(path string) string {
+	parent := pth.Dir(path)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
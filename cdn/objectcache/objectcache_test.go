@@ -0,0 +1,147 @@
+package objectcache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"testing"
+	"time"
+)
+
+func newTestObjectCache() *ObjectCache {
+	return NewObjectCache(conf.ObjectCacheYaml{MaxSize: 1024})
+}
+
+func TestObjectFillStreamsBeforeCompletion(t *testing.T) {
+	oc := newTestObjectCache()
+	key := Key{Zone: "z", Path: "a.txt"}
+	modified := time.Now()
+	release := make(chan struct{})
+	fill := oc.StartFill(key, 10, modified, func(w io.Writer) error {
+		_, _ = w.Write([]byte("hello"))
+		<-release
+		_, err := w.Write([]byte("world"))
+		return err
+	})
+	if other := oc.StartFill(key, 10, modified, nil); other != fill {
+		t.Fatal("expected concurrent requests to share the fill")
+	}
+
+	firstChunk := make(chan string, 1)
+	result := make(chan string, 1)
+	go func() {
+		buf := new(bytes.Buffer)
+		_, ok, _ := fill.StreamTo(writerFunc(func(p []byte) (int, error) {
+			if buf.Len() == 0 {
+				firstChunk <- string(p)
+			}
+			return buf.Write(p)
+		}))
+		if !ok {
+			result <- "failed"
+			return
+		}
+		result <- buf.String()
+	}()
+
+	select {
+	case chunk := <-firstChunk:
+		if chunk != "hello" {
+			t.Fatalf("unexpected first chunk %q", chunk)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the buffered bytes to be streamed before the fill completed")
+	}
+	close(release)
+	if content := <-result; content != "helloworld" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if data, ok := oc.Get(key, 10, modified); ok {
+			if string(data) != "helloworld" {
+				t.Fatalf("unexpected cached data %q", data)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the completed fill to be cached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestObjectFillFailure(t *testing.T) {
+	oc := newTestObjectCache()
+	key := Key{Zone: "z", Path: "a.txt"}
+	fill := oc.StartFill(key, 10, time.Now(), func(w io.Writer) error {
+		_, _ = w.Write([]byte("hel"))
+		return errors.New("backend failed")
+	})
+	buf := new(bytes.Buffer)
+	n, ok, err := fill.StreamTo(buf)
+	if ok || err != nil || n != 3 || buf.String() != "hel" {
+		t.Fatalf("expected a partial failed fill, got %d %v %v %q", n, ok, err, buf.String())
+	}
+}
+
+func TestObjectCacheInvalidate(t *testing.T) {
+	oc := newTestObjectCache()
+	modified := time.Now()
+	keys := []Key{
+		{Zone: "z", Path: "a.txt"},
+		{Zone: "z", Path: "dir/b.txt"},
+		{Zone: "z", Path: "dir/sub/c.txt"},
+		{Zone: "z", Path: "dirt.txt"},
+		{Zone: "other", Path: "dir/b.txt"},
+	}
+	for _, k := range keys {
+		oc.Put(k, []byte("data"), modified)
+	}
+
+	oc.Invalidate(Key{Zone: "z", Path: "dir"})
+	for _, k := range keys {
+		_, ok := oc.Get(k, 4, modified)
+		removed := k.Zone == "z" && (k.Path == "dir/b.txt" || k.Path == "dir/sub/c.txt")
+		if ok == removed {
+			t.Fatalf("unexpected cache state for %v: cached %v", k, ok)
+		}
+	}
+
+	oc.Invalidate(Key{Zone: "z", Path: ""})
+	if _, _, size, entries := oc.Stats(); size != 4 || entries != 1 {
+		t.Fatalf("expected only the other zone to remain, got %d bytes in %d entries", size, entries)
+	}
+	if len(oc.entryDirs) != 2 {
+		t.Fatalf("expected the directory index to be cleaned up, got %v", oc.entryDirs)
+	}
+}
+
+func TestObjectCacheInvalidatedFillIsNotStored(t *testing.T) {
+	oc := newTestObjectCache()
+	key := Key{Zone: "z", Path: "dir/a.txt"}
+	modified := time.Now()
+	release := make(chan struct{})
+	fill := oc.StartFill(key, 4, modified, func(w io.Writer) error {
+		<-release
+		_, err := w.Write([]byte("data"))
+		return err
+	})
+	oc.Invalidate(Key{Zone: "z", Path: "dir"})
+	close(release)
+	if _, ok, _ := fill.StreamTo(io.Discard); !ok {
+		t.Fatal("expected the waiting request to still receive the object")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := oc.Get(key, 4, modified); ok {
+		t.Fatal("expected the invalidated fill to not be cached")
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
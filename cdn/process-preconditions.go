@@ -23,60 +23,48 @@ func processSupportedPreconditions429(rw http.ResponseWriter, req *http.Request,
 }
 
 func processSupportedPreconditions(statusCode int, statusMessage string, rw http.ResponseWriter, req *http.Request, modT time.Time, etag string, noBypassModify bool, noBypassMatch bool) bool {
-	theStrippedETag := utils.GetETagValue(etag)
-	if noBypassMatch && theStrippedETag != "" && req.Header.Get("If-None-Match") != "" {
-		eTagValues := utils.GetETagValues(req.Header.Get("If-None-Match"))
-		conditionSuccess := false
+	lastModified := modT.Truncate(time.Second)
+	ifMatch := noBypassMatch && req.Header.Get("If-Match") != ""
+	ifNoneMatch := noBypassMatch && req.Header.Get("If-None-Match") != ""
+
+	if ifMatch {
+		eTagValues, matchAny := utils.ParseETagList(req.Header.Get("If-Match"))
+		conditionSuccess := matchAny
 		for _, s := range eTagValues {
-			if s == theStrippedETag {
+			if utils.ETagStrongMatch(etag, s) {
 				conditionSuccess = true
 				break
 			}
 		}
-		if conditionSuccess {
-			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotModified, "")
-			utils.LogPrintln(4, "Send Skipped")
-			return false
+		if !conditionSuccess {
+			return writePreconditionFailed(rw, req)
+		}
+	} else if noBypassModify && !modT.IsZero() && req.Header.Get("If-Unmodified-Since") != "" {
+		parse, err := http.ParseTime(req.Header.Get("If-Unmodified-Since"))
+		if err == nil && lastModified.After(parse) {
+			return writePreconditionFailed(rw, req)
 		}
 	}
 
-	if noBypassMatch && theStrippedETag != "" && req.Header.Get("If-Match") != "" {
-		eTagValues := utils.GetETagValues(req.Header.Get("If-Match"))
-		conditionFailed := true
+	if ifNoneMatch {
+		eTagValues, matchAny := utils.ParseETagList(req.Header.Get("If-None-Match"))
+		conditionFailed := matchAny
 		for _, s := range eTagValues {
-			if s == theStrippedETag {
-				conditionFailed = false
+			if utils.ETagWeakMatch(etag, s) {
+				conditionFailed = true
 				break
 			}
 		}
 		if conditionFailed {
-			utils.SwitchToNonCachingHeaders(rw.Header())
-			rw.Header().Del("Content-Type")
-			rw.Header().Del("Content-Length")
-			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusPreconditionFailed, "")
-			utils.LogPrintln(4, "Send Condition Not Satisfied")
-			return false
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				return writeNotModified(rw, req)
+			}
+			return writePreconditionFailed(rw, req)
 		}
-	}
-
-	if noBypassModify && !modT.IsZero() && req.Header.Get("If-Modified-Since") != "" {
-		parse, err := time.Parse(http.TimeFormat, req.Header.Get("If-Modified-Since"))
-		if err == nil && modT.Before(parse) || strings.EqualFold(modT.Format(http.TimeFormat), req.Header.Get("If-Modified-Since")) {
-			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotModified, "")
-			utils.LogPrintln(4, "Send Skipped")
-			return false
-		}
-	}
-
-	if noBypassModify && !modT.IsZero() && req.Header.Get("If-Unmodified-Since") != "" {
-		parse, err := time.Parse(http.TimeFormat, req.Header.Get("If-Unmodified-Since"))
-		if err == nil && modT.After(parse) {
-			utils.SwitchToNonCachingHeaders(rw.Header())
-			rw.Header().Del("Content-Type")
-			rw.Header().Del("Content-Length")
-			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusPreconditionFailed, "")
-			utils.LogPrintln(4, "Send Condition Not Satisfied")
-			return false
+	} else if noBypassModify && !modT.IsZero() && req.Header.Get("If-Modified-Since") != "" && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		parse, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+		if err == nil && !lastModified.After(parse) {
+			return writeNotModified(rw, req)
 		}
 	}
 
@@ -87,24 +75,45 @@ func processSupportedPreconditions(statusCode int, statusMessage string, rw http
 	}
 }
 
+func processMutationPreconditions(rw http.ResponseWriter, req *http.Request, exists bool, modT time.Time, etag string) bool {
+	if !exists {
+		if req.Header.Get("If-Match") != "" {
+			return writePreconditionFailed(rw, req)
+		}
+		return true
+	}
+	return processSupportedPreconditionsForNext(rw, req, modT, etag, true, true)
+}
+
+func writeNotModified(rw http.ResponseWriter, req *http.Request) bool {
+	writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotModified, "")
+	utils.LogPrintln(4, "Send Skipped")
+	return false
+}
+
+func writePreconditionFailed(rw http.ResponseWriter, req *http.Request) bool {
+	utils.SwitchToNonCachingHeaders(rw.Header())
+	rw.Header().Del("Content-Type")
+	rw.Header().Del("Content-Length")
+	rw.Header().Del("Content-Encoding")
+	writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusPreconditionFailed, "")
+	utils.LogPrintln(4, "Send Condition Not Satisfied")
+	return false
+}
+
 func processRangePreconditions(maxLength int64, rw http.ResponseWriter, req *http.Request, modT time.Time, etag string, supported bool) []utils.ContentRangeValue {
 	canDoRange := supported
-	theStrippedETag := utils.GetETagValue(etag)
-	modTStr := modT.Format(http.TimeFormat)
 
 	if canDoRange {
 		rw.Header().Set("Accept-Ranges", "bytes")
 	}
 
-	if canDoRange && !modT.IsZero() && strings.HasSuffix(req.Header.Get("If-Range"), "GMT") {
-		newModT, err := time.Parse(http.TimeFormat, modTStr)
-		parse, err := time.Parse(http.TimeFormat, req.Header.Get("If-Range"))
-		if err == nil && !newModT.Equal(parse) {
-			canDoRange = false
-		}
-	} else if canDoRange && theStrippedETag != "" && req.Header.Get("If-Range") != "" {
-		if utils.GetETagValue(req.Header.Get("If-Range")) != theStrippedETag {
-			canDoRange = false
+	if ifRange := strings.TrimSpace(req.Header.Get("If-Range")); canDoRange && ifRange != "" {
+		if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+			canDoRange = utils.ETagStrongMatch(etag, ifRange)
+		} else {
+			parse, err := http.ParseTime(ifRange)
+			canDoRange = err == nil && !modT.IsZero() && modT.Truncate(time.Second).Equal(parse)
 		}
 	}
 
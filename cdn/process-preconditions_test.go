@@ -0,0 +1,134 @@
+package cdn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProcessSupportedPreconditions(t *testing.T) {
+	modT := time.Date(2023, 10, 1, 12, 0, 0, 500, time.UTC)
+	etag := "\"abc\""
+	before := modT.Add(-time.Hour).Format(http.TimeFormat)
+	after := modT.Add(time.Hour).Format(http.TimeFormat)
+	same := modT.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"no preconditions", http.MethodGet, nil, 0},
+		{"if-match strong match", http.MethodGet, map[string]string{"If-Match": "\"abc\""}, 0},
+		{"if-match in list", http.MethodGet, map[string]string{"If-Match": "\"x\", \"abc\""}, 0},
+		{"if-match any", http.MethodPut, map[string]string{"If-Match": "*"}, 0},
+		{"if-match mismatch", http.MethodGet, map[string]string{"If-Match": "\"x\""}, http.StatusPreconditionFailed},
+		{"if-match weak never matches", http.MethodGet, map[string]string{"If-Match": "W/\"abc\""}, http.StatusPreconditionFailed},
+		{"if-match overrides if-unmodified-since", http.MethodPut, map[string]string{"If-Match": "\"abc\"", "If-Unmodified-Since": before}, 0},
+		{"if-unmodified-since before", http.MethodPut, map[string]string{"If-Unmodified-Since": before}, http.StatusPreconditionFailed},
+		{"if-unmodified-since same second", http.MethodPut, map[string]string{"If-Unmodified-Since": same}, 0},
+		{"if-unmodified-since invalid date", http.MethodPut, map[string]string{"If-Unmodified-Since": "yesterday"}, 0},
+		{"if-none-match get", http.MethodGet, map[string]string{"If-None-Match": "\"abc\""}, http.StatusNotModified},
+		{"if-none-match weak compare", http.MethodHead, map[string]string{"If-None-Match": "W/\"abc\""}, http.StatusNotModified},
+		{"if-none-match mismatch", http.MethodGet, map[string]string{"If-None-Match": "\"x\""}, 0},
+		{"if-none-match put", http.MethodPut, map[string]string{"If-None-Match": "\"abc\""}, http.StatusPreconditionFailed},
+		{"if-none-match any put", http.MethodPut, map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-none-match any delete", http.MethodDelete, map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-none-match overrides if-modified-since", http.MethodGet, map[string]string{"If-None-Match": "\"x\"", "If-Modified-Since": after}, 0},
+		{"if-modified-since after", http.MethodGet, map[string]string{"If-Modified-Since": after}, http.StatusNotModified},
+		{"if-modified-since same second", http.MethodGet, map[string]string{"If-Modified-Since": same}, http.StatusNotModified},
+		{"if-modified-since before", http.MethodGet, map[string]string{"If-Modified-Since": before}, 0},
+		{"if-modified-since ignored for put", http.MethodPut, map[string]string{"If-Modified-Since": after}, 0},
+		{"if-match evaluated before if-none-match", http.MethodGet, map[string]string{"If-Match": "\"x\"", "If-None-Match": "\"abc\""}, http.StatusPreconditionFailed},
+		{"if-unmodified-since evaluated before if-none-match", http.MethodGet, map[string]string{"If-Unmodified-Since": before, "If-None-Match": "\"abc\""}, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/zone/object", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			ok := processSupportedPreconditionsForNext(rec, req, modT, etag, true, true)
+			if tt.status == 0 {
+				if !ok {
+					t.Fatalf("expected preconditions to pass, got %d", rec.Code)
+				}
+			} else if ok || rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d (passed: %v)", tt.status, rec.Code, ok)
+			}
+		})
+	}
+}
+
+func TestProcessMutationPreconditions(t *testing.T) {
+	modT := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	etag := "\"abc\""
+
+	tests := []struct {
+		name    string
+		exists  bool
+		headers map[string]string
+		status  int
+	}{
+		{"create without preconditions", false, nil, 0},
+		{"create only on missing object", false, map[string]string{"If-None-Match": "*"}, 0},
+		{"create only on existing object", true, map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-match any on missing object", false, map[string]string{"If-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-match on missing object", false, map[string]string{"If-Match": "\"abc\""}, http.StatusPreconditionFailed},
+		{"if-match on existing object", true, map[string]string{"If-Match": "\"abc\""}, 0},
+		{"stale if-match on existing object", true, map[string]string{"If-Match": "\"old\""}, http.StatusPreconditionFailed},
+		{"if-unmodified-since on missing object", false, map[string]string{"If-Unmodified-Since": modT.Add(-time.Hour).Format(http.TimeFormat)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/zone/object", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			ok := processMutationPreconditions(rec, req, tt.exists, modT, etag)
+			if tt.status == 0 {
+				if !ok {
+					t.Fatalf("expected preconditions to pass, got %d", rec.Code)
+				}
+			} else if ok || rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d (passed: %v)", tt.status, rec.Code, ok)
+			}
+		})
+	}
+}
+
+func TestProcessRangePreconditions(t *testing.T) {
+	modT := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	etag := "\"abc\""
+
+	tests := []struct {
+		name    string
+		ifRange string
+		status  int
+	}{
+		{"no if-range", "", http.StatusPartialContent},
+		{"if-range strong match", "\"abc\"", http.StatusPartialContent},
+		{"if-range mismatch", "\"x\"", http.StatusOK},
+		{"if-range weak etag", "W/\"abc\"", http.StatusOK},
+		{"if-range exact date", modT.Format(http.TimeFormat), http.StatusPartialContent},
+		{"if-range other date", modT.Add(time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/zone/object", nil)
+			req.Header.Set("Range", "bytes=0-3")
+			if tt.ifRange != "" {
+				req.Header.Set("If-Range", tt.ifRange)
+			}
+			rec := httptest.NewRecorder()
+			processRangePreconditions(10, rec, req, modT, etag, true)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strings"
+)
+
+func GetClientIP(req *http.Request, trustedProxies []string, clientIPHeader string) string {
+	remoteIP := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if len(trustedProxies) == 0 || !conf.AddressMatches(trustedProxies, remoteIP) {
+		return remoteIP
+	}
+	switch strings.ToLower(clientIPHeader) {
+	case "", "x-forwarded-for":
+		var hops []string
+		for _, h := range req.Header.Values("X-Forwarded-For") {
+			for _, s := range strings.Split(h, ",") {
+				hops = append(hops, strings.TrimSpace(s))
+			}
+		}
+		return getClientIPFromHops(remoteIP, hops, trustedProxies)
+	case "x-real-ip":
+		if addr, err := netip.ParseAddr(strings.TrimSpace(req.Header.Get("X-Real-IP"))); err == nil {
+			return addr.Unmap().String()
+		}
+	case "forwarded":
+		var hops []string
+		for _, h := range req.Header.Values("Forwarded") {
+			for _, element := range strings.Split(h, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					if k, v, ok := strings.Cut(strings.TrimSpace(pair), "="); ok && strings.EqualFold(k, "for") {
+						hops = append(hops, getForwardedNodeAddress(v))
+					}
+				}
+			}
+		}
+		return getClientIPFromHops(remoteIP, hops, trustedProxies)
+	}
+	return remoteIP
+}
+
+func getClientIPFromHops(remoteIP string, hops []string, trustedProxies []string) string {
+	clientIP := remoteIP
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		clientIP = addr.Unmap().String()
+		if !conf.AddressMatches(trustedProxies, clientIP) {
+			break
+		}
+	}
+	return clientIP
+}
+
+func getForwardedNodeAddress(node string) string {
+	node = strings.Trim(strings.TrimSpace(node), "\"")
+	if strings.HasPrefix(node, "[") {
+		if idx := strings.Index(node, "]"); idx > 0 {
+			return node[1:idx]
+		}
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	theEncoding := ""
+	var theQuality float64
+	var wildcardQuality float64 = -1
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if name == "*" {
+			wildcardQuality = quality
+		} else {
+			qualities[name] = quality
+		}
+	}
+	for _, s := range supported {
+		if !EncodingSupported(s) {
+			continue
+		}
+		quality, ok := qualities[s]
+		if !ok {
+			quality = wildcardQuality
+		}
+		if quality > theQuality {
+			theEncoding = s
+			theQuality = quality
+		}
+	}
+	return theEncoding
+}
+
+var SupportedEncodings = []string{"br", "zstd", "gzip"}
+
+func EncodingSupported(encoding string) bool {
+	for _, e := range SupportedEncodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+func GetEncodingExtension(encoding string) string {
+	switch encoding {
+	case "gzip":
+		return ".gz"
+	case "br":
+		return ".br"
+	case "zstd":
+		return ".zst"
+	}
+	return ""
+}
+
+func NewEncodingWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "br":
+		return brotli.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return nil, errors.New("unsupported encoding: " + encoding)
+}
+
+func GetETagForEncoding(eTag string, encoding string) string {
+	if encoding == "" || !strings.HasSuffix(eTag, "\"") {
+		return eTag
+	}
+	return eTag[:len(eTag)-1] + "-" + encoding + "\""
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"testing"
+)
+
+func TestNewEncodingWriter(t *testing.T) {
+	decoders := map[string]func(r io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for _, encoding := range SupportedEncodings {
+		t.Run(encoding, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			theWriter, err := NewEncodingWriter(encoding, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = theWriter.Write([]byte("hello world"))
+			if err := theWriter.Close(); err != nil {
+				t.Fatal(err)
+			}
+			theReader, err := decoders[encoding](buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(theReader)
+			if err != nil || string(content) != "hello world" {
+				t.Fatalf("unexpected round trip %q: %v", content, err)
+			}
+		})
+	}
+	if _, err := NewEncodingWriter("compress", io.Discard); err == nil {
+		t.Fatal("expected an error for an unsupported encoding")
+	}
+}
@@ -22,35 +22,43 @@ func GetValueForETagUsingAttributes(timeIn time.Time, sizeIn int64) string {
 	}
 }
 
-func GetETagValues(stringIn string) []string {
-	if strings.ContainsAny(stringIn, ",") {
-		seperated := strings.Split(stringIn, ",")
-		toReturn := make([]string, len(seperated))
-		pos := 0
-		for _, s := range seperated {
-			cETag := GetETagValue(s)
-			if cETag != "" {
-				toReturn[pos] = cETag
-				pos += 1
+func ParseETagList(stringIn string) (eTags []string, matchAny bool) {
+	stringIn = strings.TrimSpace(stringIn)
+	if stringIn == "*" {
+		return nil, true
+	}
+	for len(stringIn) > 0 {
+		stringIn = strings.TrimLeft(stringIn, " \t,")
+		weakPrefix := ""
+		if strings.HasPrefix(stringIn, "W/") {
+			weakPrefix = "W/"
+			stringIn = stringIn[2:]
+		}
+		if !strings.HasPrefix(stringIn, "\"") {
+			if idx := strings.IndexByte(stringIn, ','); idx > -1 {
+				stringIn = stringIn[idx+1:]
+				continue
 			}
+			break
 		}
-		if pos == 0 {
-			return nil
+		endIndex := strings.IndexByte(stringIn[1:], '"')
+		if endIndex < 0 {
+			break
 		}
-		return toReturn[:pos]
+		eTags = append(eTags, weakPrefix+stringIn[:endIndex+2])
+		stringIn = stringIn[endIndex+2:]
 	}
-	toReturn := []string{GetETagValue(stringIn)}
-	if toReturn[0] == "" {
-		return nil
-	}
-	return toReturn
+	return eTags, false
 }
 
-func GetETagValue(stringIn string) string {
-	startIndex := strings.IndexAny(stringIn, "\"") + 1
-	endIndex := strings.LastIndexAny(stringIn, "\"")
-	if endIndex > startIndex {
-		return stringIn[startIndex:endIndex]
-	}
-	return ""
+func IsWeakETag(eTag string) bool {
+	return strings.HasPrefix(eTag, "W/")
+}
+
+func ETagStrongMatch(a string, b string) bool {
+	return a != "" && !IsWeakETag(a) && !IsWeakETag(b) && a == b
+}
+
+func ETagWeakMatch(a string, b string) bool {
+	return a != "" && strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
 }
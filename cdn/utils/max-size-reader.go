@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"errors"
+	"io"
+)
+
+func NewMaxSizeReader(readerIn io.Reader, maxSize int64) *MaxSizeReader {
+	return &MaxSizeReader{
+		passedReader: readerIn,
+		remaining:    maxSize,
+	}
+}
+
+type MaxSizeReader struct {
+	passedReader io.Reader
+	remaining    int64
+	Exceeded     bool
+}
+
+func (r *MaxSizeReader) Read(p []byte) (n int, err error) {
+	if r.remaining <= 0 {
+		var single [1]byte
+		if read, _ := r.passedReader.Read(single[:]); read > 0 {
+			r.Exceeded = true
+			return 0, errors.New("maximum size exceeded")
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err = r.passedReader.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
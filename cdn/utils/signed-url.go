@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func GetUrlSignature(secret string, lookupPath string, expires int64, clientIP string) string {
+	theHash := hmac.New(sha256.New, []byte(secret))
+	_, _ = theHash.Write([]byte(lookupPath + "\n" + strconv.FormatInt(expires, 10) + "\n" + clientIP))
+	return base64.RawURLEncoding.EncodeToString(theHash.Sum(nil))
+}
+
+func SignUrlQuery(secret string, lookupPath string, expireTime time.Time, clientIP string) string {
+	expires := expireTime.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", GetUrlSignature(secret, lookupPath, expires, clientIP))
+	return query.Encode()
+}
+
+func UrlSignatureValid(secret string, lookupPath string, query url.Values, clientIP string) bool {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	theSignature, err := base64.RawURLEncoding.DecodeString(query.Get("signature"))
+	if err != nil {
+		return false
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(GetUrlSignature(secret, lookupPath, expires, clientIP))
+	return hmac.Equal(theSignature, expected)
+}
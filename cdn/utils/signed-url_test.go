@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUrlSignatureValid(t *testing.T) {
+	const secret = "signing-secret"
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Minute).Unix()
+
+	signed := func(lookupPath string, expires int64, clientIP string) url.Values {
+		return url.Values{
+			"expires":   {strconv.FormatInt(expires, 10)},
+			"signature": {GetUrlSignature(secret, lookupPath, expires, clientIP)},
+		}
+	}
+	withSignature := func(query url.Values, signature string) url.Values {
+		query.Set("signature", signature)
+		return query
+	}
+	validSignature := GetUrlSignature(secret, "dir/file.txt", future, "")
+	rawSignature, _ := base64.RawURLEncoding.DecodeString(validSignature)
+	flipped := append([]byte{}, rawSignature...)
+	flipped[0] ^= 0x01
+
+	tests := []struct {
+		name       string
+		lookupPath string
+		query      url.Values
+		clientIP   string
+		valid      bool
+	}{
+		{"valid", "dir/file.txt", signed("dir/file.txt", future, ""), "", true},
+		{"expired", "dir/file.txt", signed("dir/file.txt", past, ""), "", false},
+		{"other path", "dir/other.txt", signed("dir/file.txt", future, ""), "", false},
+		{"parent path", "dir", signed("dir/file.txt", future, ""), "", false},
+		{"extended expiry", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future+3600, 10)}}, validSignature), "", false},
+		{"wrong secret", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, GetUrlSignature("other-secret", "dir/file.txt", future, "")), "", false},
+		{"flipped bit", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, base64.RawURLEncoding.EncodeToString(flipped)), "", false},
+		{"truncated signature", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, validSignature[:len(validSignature)-4]), "", false},
+		{"empty signature", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, ""), "", false},
+		{"padded signature", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, validSignature+"="), "", false},
+		{"invalid base64", "dir/file.txt", withSignature(url.Values{"expires": {strconv.FormatInt(future, 10)}}, "!!!!"), "", false},
+		{"missing expiry", "dir/file.txt", url.Values{"signature": {validSignature}}, "", false},
+		{"invalid expiry", "dir/file.txt", withSignature(url.Values{"expires": {"tomorrow"}}, validSignature), "", false},
+		{"missing query", "dir/file.txt", url.Values{}, "", false},
+		{"bound client", "dir/file.txt", signed("dir/file.txt", future, "203.0.113.7"), "203.0.113.7", true},
+		{"other client", "dir/file.txt", signed("dir/file.txt", future, "203.0.113.7"), "203.0.113.8", false},
+		{"unbound signature for bound check", "dir/file.txt", signed("dir/file.txt", future, ""), "203.0.113.7", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := UrlSignatureValid(secret, tt.lookupPath, tt.query, tt.clientIP); valid != tt.valid {
+				t.Fatalf("expected %v, got %v", tt.valid, valid)
+			}
+		})
+	}
+}
+
+func TestSignUrlQuery(t *testing.T) {
+	const secret = "signing-secret"
+	query, err := url.ParseQuery(SignUrlQuery(secret, "a/b.txt", time.Now().Add(time.Minute), "198.51.100.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !UrlSignatureValid(secret, "a/b.txt", query, "198.51.100.2") {
+		t.Fatal("expected the generated query to be valid")
+	}
+	if UrlSignatureValid(secret, "a/b.txt", query, "") {
+		t.Fatal("expected the generated query to be bound to the client address")
+	}
+}
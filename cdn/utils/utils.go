@@ -47,6 +47,35 @@ func SetCacheHeaderWithAge(header http.Header, maxAge uint, modifiedTime time.Ti
 	}
 }
 
+func SetRetryAfterHeader(header http.Header, retryAfter time.Duration) {
+	header.Set("Retry-After", strconv.FormatInt(durationToSeconds(retryAfter, 1), 10))
+}
+
+func SetRateLimitHeaders(header http.Header, limit uint, remaining uint, reset time.Duration) {
+	header.Set("RateLimit-Limit", strconv.FormatUint(uint64(limit), 10))
+	header.Set("RateLimit-Remaining", strconv.FormatUint(uint64(remaining), 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(durationToSeconds(reset, 0), 10))
+}
+
+func durationToSeconds(d time.Duration, minSeconds int64) int64 {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < minSeconds {
+		return minSeconds
+	}
+	return seconds
+}
+
+func AddVaryHeader(header http.Header, fieldName string) {
+	for _, v := range header.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), fieldName) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", fieldName)
+}
+
 func SwitchToNonCachingHeaders(header http.Header) {
 	SetNeverCacheHeader(header)
 	if header.Get("Last-Modified") != "" {
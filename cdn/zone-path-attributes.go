@@ -10,6 +10,7 @@ func NewZonePathAttributes(lModTime time.Time, eTag string) *ZonePathAttributes
 		lastModifiedTime: lModTime,
 		eTag:             eTag,
 		NotExpunged:      true,
+		lastUsed:         time.Now(),
 	}
 }
 
@@ -22,10 +23,27 @@ type ZonePathAttributes struct {
 	age              string
 	expire           string
 	NotExpunged      bool
+	lastUsed         time.Time
+}
+
+func (zpa *ZonePathAttributes) Active() bool {
+	return false
+}
+
+func (zpa *ZonePathAttributes) InUse() bool {
+	return false
+}
+
+func (zpa *ZonePathAttributes) GetLastUsed() time.Time {
+	if zpa == nil {
+		return time.Time{}
+	}
+	return zpa.lastUsed
 }
 
 func (zpa *ZonePathAttributes) Update(lModTime time.Time, eTag string, header http.Header) {
 	zpa.NotExpunged = true
+	zpa.lastUsed = time.Now()
 	zpa.lastModifiedTime = lModTime
 	zpa.eTag = eTag
 	zpa.contentLength = header.Get("Content-Length")
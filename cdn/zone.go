@@ -1,55 +1,85 @@
 package cdn
 
 import (
-	"github.com/tomasen/realip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
 	"path"
 	"snow.mrmelon54.xyz/snowedin/cdn/limits"
+	"snow.mrmelon54.xyz/snowedin/cdn/objectcache"
 	"snow.mrmelon54.xyz/snowedin/cdn/utils"
 	"snow.mrmelon54.xyz/snowedin/conf"
+	snowutils "snow.mrmelon54.xyz/snowedin/utils"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
-func NewZone(conf conf.ZoneYaml, logLevel uint) *Zone {
+func NewZone(conf conf.ZoneYaml, listenConf conf.ListenYaml, cdnLimits *limits.GlobalLimits, cdnBandwidthBuckets *limits.BandwidthBuckets, cdnObjectCache *objectcache.ObjectCache, logLevel uint) *Zone {
 	var thePathAttributes map[string]*ZonePathAttributes
 	if conf.CacheResponse.RequestLimitedCacheCheck {
 		thePathAttributes = make(map[string]*ZonePathAttributes)
 	}
+	var theObjectCache *objectcache.ObjectCache
+	if conf.UseObjectCache {
+		theObjectCache = cdnObjectCache
+	}
 	cZone := &Zone{
-		Config:           conf,
-		Backend:          NewBackendFromName(conf.Backend, conf.BackendSettings),
-		mutAccess:        new(sync.RWMutex),
-		mutRequest:       new(sync.RWMutex),
-		mutConn:          new(sync.RWMutex),
-		mutPathAttr:      new(sync.RWMutex),
-		AccessLimits:     make(map[string]*limits.AccessLimit),
-		RequestLimits:    make(map[string]*limits.RequestLimit),
-		ConnectionLimits: make(map[string]*limits.ConnectionLimit),
-		PathAttributes:   thePathAttributes,
+		Config:              conf,
+		ListenConfig:        listenConf,
+		Backend:             NewBackendFromName(conf.Backend, conf.BackendSettings),
+		mutAccess:           new(sync.RWMutex),
+		mutRequest:          new(sync.RWMutex),
+		mutConn:             new(sync.RWMutex),
+		mutPathAttr:         new(sync.RWMutex),
+		AccessLimits:        make(map[limits.AccessLimitKey]*limits.AccessLimit),
+		accessLimitPaths:    make(map[string]map[limits.AccessLimitKey]struct{}),
+		RequestLimits:       make(map[string]*limits.RequestLimit),
+		ConnectionLimits:    make(map[string]*limits.ConnectionLimit),
+		PathAttributes:      thePathAttributes,
+		GlobalLimits:        limits.NewGlobalLimits(conf.GlobalLimits),
+		CDNLimits:           cdnLimits,
+		BandwidthBuckets:    limits.NewBandwidthBuckets(),
+		CDNBandwidthBuckets: cdnBandwidthBuckets,
+		ObjectCache:         theObjectCache,
 	}
 	if cZone.Backend == nil {
 		return nil
 	}
 	utils.LogLevel = logLevel
+	if conf.AccessLimit.StateFile != "" {
+		err := cZone.loadAccessLimitState()
+		if err != nil {
+			utils.LogPrintln(1, "Access Limit State Load Error: "+err.Error())
+		}
+		go cZone.runAccessLimitStateFlusher()
+	}
 	return cZone
 }
 
 type Zone struct {
-	Config           conf.ZoneYaml
-	Backend          Backend
-	mutAccess        *sync.RWMutex
-	mutRequest       *sync.RWMutex
-	mutConn          *sync.RWMutex
-	mutPathAttr      *sync.RWMutex
-	AccessLimits     map[string]*limits.AccessLimit
-	RequestLimits    map[string]*limits.RequestLimit
-	ConnectionLimits map[string]*limits.ConnectionLimit
-	PathAttributes   map[string]*ZonePathAttributes
+	Config              conf.ZoneYaml
+	ListenConfig        conf.ListenYaml
+	Backend             Backend
+	mutAccess           *sync.RWMutex
+	mutRequest          *sync.RWMutex
+	mutConn             *sync.RWMutex
+	mutPathAttr         *sync.RWMutex
+	AccessLimits        map[limits.AccessLimitKey]*limits.AccessLimit
+	accessLimitPaths    map[string]map[limits.AccessLimitKey]struct{}
+	RequestLimits       map[string]*limits.RequestLimit
+	ConnectionLimits    map[string]*limits.ConnectionLimit
+	PathAttributes      map[string]*ZonePathAttributes
+	GlobalLimits        *limits.GlobalLimits
+	CDNLimits           *limits.GlobalLimits
+	BandwidthBuckets    *limits.BandwidthBuckets
+	CDNBandwidthBuckets *limits.BandwidthBuckets
+	ObjectCache         *objectcache.ObjectCache
 }
 
 func (zone *Zone) checkRequestLimits(clientIP string) *limits.RequestLimit {
@@ -60,6 +90,8 @@ func (zone *Zone) checkRequestLimits(clientIP string) *limits.RequestLimit {
 		a = limits.NewRequestLimit(reqLimit)
 		zone.RequestLimits[clientIP] = a
 	}
+	a.LastUsed = time.Now()
+	a.Acquire()
 	zone.mutRequest.Unlock()
 	return a
 }
@@ -72,21 +104,119 @@ func (zone *Zone) checkConnectionLimits(clientIP string) *limits.ConnectionLimit
 		a = limits.NewConnectionLimit(connLimit)
 		zone.ConnectionLimits[clientIP] = a
 	}
+	a.LastUsed = time.Now()
+	a.Acquire()
 	zone.mutConn.Unlock()
 	return a
 }
 
-func (zone *Zone) checkAccessLimits(lookupPath string) *limits.AccessLimit {
+func (zone *Zone) checkAccessLimits(accessKey limits.AccessLimitKey, accessLimit conf.AccessLimitYaml) *limits.AccessLimit {
 	zone.mutAccess.Lock()
-	a := zone.AccessLimits[lookupPath]
+	a := zone.AccessLimits[accessKey]
 	if a == nil {
-		a = limits.NewAccessLimit(zone.Config.AccessLimit)
-		zone.AccessLimits[lookupPath] = a
+		a = limits.NewAccessLimit(accessLimit)
+		zone.addAccessLimit(accessKey, a)
 	}
+	a.LastUsed = time.Now()
+	a.Acquire()
 	zone.mutAccess.Unlock()
 	return a
 }
 
+var newEncodingWriter = utils.NewEncodingWriter
+
+func (zone *Zone) negotiateEncoding(header http.Header, req *http.Request, mimeType string, size int64) string {
+	if !zone.Config.Compression.Enabled || !zone.Config.Compression.MimeTypeCompressible(mimeType) {
+		return ""
+	}
+	utils.AddVaryHeader(header, "Accept-Encoding")
+	if size < zone.Config.Compression.MinSize || header.Get("Content-Encoding") != "" || (zone.Config.AllowRange && req.Header.Get("Range") != "") {
+		return ""
+	}
+	return utils.NegotiateEncoding(req.Header.Get("Accept-Encoding"), zone.Config.Compression.GetEncodings())
+}
+
+func (zone *Zone) negotiatePrecompressed(header http.Header, req *http.Request, lookupPath string) (encoding string, objectPath string) {
+	pBackend, ok := zone.Backend.(PrecompressedBackend)
+	if !ok || header.Get("Content-Encoding") != "" {
+		return "", ""
+	}
+	available := pBackend.PrecompressedEncodings(lookupPath)
+	if len(available) == 0 {
+		return "", ""
+	}
+	utils.AddVaryHeader(header, "Accept-Encoding")
+	var supported []string
+	for _, e := range zone.Config.Compression.GetEncodings() {
+		for _, a := range available {
+			if e == a {
+				supported = append(supported, e)
+			}
+		}
+	}
+	encoding = utils.NegotiateEncoding(req.Header.Get("Accept-Encoding"), supported)
+	if encoding == "" {
+		return "", ""
+	}
+	return encoding, lookupPath + utils.GetEncodingExtension(encoding)
+}
+
+func (zone *Zone) getObjectPolicy(lookupPath string) conf.ObjectPolicyYaml {
+	if pBackend, ok := zone.Backend.(PolicyBackend); ok {
+		return pBackend.Policy(lookupPath)
+	}
+	return conf.ObjectPolicyYaml{}
+}
+
+func setPolicyHeaders(header http.Header, objectPolicy conf.ObjectPolicyYaml) {
+	if objectPolicy.CacheControl != "" {
+		header.Set("Cache-Control", objectPolicy.CacheControl)
+	}
+	for k, v := range objectPolicy.Headers {
+		header.Set(k, v)
+	}
+}
+
+func (zone *Zone) getAccessLimitKey(req *http.Request, clientIP string, lookupPath string) (limits.AccessLimitKey, bool) {
+	theScope := zone.Config.AccessLimit.GetScope()
+	switch theScope {
+	case "clientIP":
+		return limits.AccessLimitKey{Path: lookupPath, Scope: theScope, Client: clientIP}, true
+	case "token":
+		if theToken := getRequestToken(req); downloadTokenValid(theToken, zone.Config.AccessLimit.DownloadTokens) {
+			theHash := sha256.Sum256([]byte(theToken))
+			return limits.AccessLimitKey{Path: lookupPath, Scope: theScope, Client: hex.EncodeToString(theHash[:])}, true
+		}
+		return limits.AccessLimitKey{}, false
+	default:
+		return limits.AccessLimitKey{Path: lookupPath, Scope: theScope}, true
+	}
+}
+
+func (zone *Zone) addAccessLimit(accessKey limits.AccessLimitKey, accessLimit *limits.AccessLimit) {
+	zone.AccessLimits[accessKey] = accessLimit
+	if zone.accessLimitPaths[accessKey.Path] == nil {
+		zone.accessLimitPaths[accessKey.Path] = make(map[limits.AccessLimitKey]struct{})
+	}
+	zone.accessLimitPaths[accessKey.Path][accessKey] = struct{}{}
+}
+
+func (zone *Zone) removeAccessLimitPath(accessKey limits.AccessLimitKey) {
+	delete(zone.accessLimitPaths[accessKey.Path], accessKey)
+	if len(zone.accessLimitPaths[accessKey.Path]) == 0 {
+		delete(zone.accessLimitPaths, accessKey.Path)
+	}
+}
+
+func (zone *Zone) clearAccessLimits(lookupPath string) {
+	zone.mutAccess.Lock()
+	for k := range zone.accessLimitPaths[lookupPath] {
+		delete(zone.AccessLimits, k)
+	}
+	delete(zone.accessLimitPaths, lookupPath)
+	zone.mutAccess.Unlock()
+}
+
 func (zone *Zone) checkPathAttributes(lookupPath string) *ZonePathAttributes {
 	zone.mutPathAttr.RLock()
 	defer zone.mutPathAttr.RUnlock()
@@ -98,31 +228,61 @@ func (zone *Zone) ZoneHandleRequest(rw http.ResponseWriter, req *http.Request) {
 		writeResponseHeaderCanWriteBody(1, req.Method, rw, http.StatusServiceUnavailable, "Zone Backend Unavailable")
 	}
 
-	clientIP := realip.FromRequest(req)
+	clientIP := utils.GetClientIP(req, zone.ListenConfig.TrustedProxies, zone.ListenConfig.ClientIPHeader)
 
-	reqLimit := zone.checkRequestLimits(clientIP)
-	connLimit := zone.checkConnectionLimits(clientIP)
+	lookupPath := strings.TrimPrefix(path.Clean(strings.TrimPrefix(req.URL.Path, "/"+zone.Config.Name+"/")), "/")
 
-	bwLim := zone.Config.Limits.GetBandwidthLimitYaml(clientIP)
-
-	if !connLimit.LimitConf.YamlValid() || connLimit.StartConnection() {
-		lookupPath := strings.TrimPrefix(path.Clean(strings.TrimPrefix(req.URL.Path, "/"+zone.Config.Name+"/")), "/")
+	if idx := strings.IndexAny(lookupPath, "?"); idx > -1 {
+		lookupPath = lookupPath[:idx]
+	}
 
-		if idx := strings.IndexAny(lookupPath, "?"); idx > -1 {
-			lookupPath = lookupPath[:idx]
+	authorized := false
+	if isMutatingMethod(req.Method) {
+		if !processAuthorization(rw, req, clientIP, zone.Config.Authorization) {
+			return
 		}
+		authorized = zone.Config.Authorization.YamlValid()
+	}
+	if !authorized && zone.Config.SignedUrls.YamlValid() && !processSignedUrl(rw, req, clientIP, lookupPath, zone.Config.SignedUrls) {
+		return
+	}
 
-		if !reqLimit.LimitConf.YamlValid() || reqLimit.StartRequest() {
-			pExists, pListTable := zone.Backend.Exists(lookupPath)
+	reqLimit := zone.checkRequestLimits(clientIP)
+	defer reqLimit.Release()
+	connLimit := zone.checkConnectionLimits(clientIP)
+	defer connLimit.Release()
 
-			if pExists {
-				assLimit := zone.checkAccessLimits(lookupPath)
+	bwLim := zone.Config.Limits.GetBandwidthLimitYaml(clientIP)
 
+	connStarted := time.Now()
+	if zone.startConnection(connLimit) {
+		if zone.startRequest(reqLimit) {
+			zone.setRateLimitHeaders(rw.Header(), reqLimit)
+			if req.Method == http.MethodPut {
+				zone.handleZonePut(rw, req, clientIP, lookupPath)
+			} else if pExists, pListTable := zone.Backend.Exists(lookupPath); pExists {
 				switch req.Method {
 				case http.MethodGet, http.MethodHead:
-					zone.handleZoneGetAndHead(rw, req, assLimit, lookupPath, pListTable, bwLim)
+					if accessKey, ok := zone.getAccessLimitKey(req, clientIP, lookupPath); ok {
+						objectPolicy := zone.getObjectPolicy(lookupPath)
+						assLimit := zone.checkAccessLimits(accessKey, objectPolicy.ApplyToAccessLimit(zone.Config.AccessLimit))
+						bwBucket := zone.getBandwidthBucket(clientIP, bwLim)
+						zone.handleZoneGetAndHead(rw, req, clientIP, assLimit, lookupPath, pListTable, bwLim, bwBucket, objectPolicy)
+						if bwBucket != nil {
+							bwBucket.Release()
+						}
+						assLimit.Release()
+					} else {
+						utils.SetNeverCacheHeader(rw.Header())
+						rw.Header().Set("WWW-Authenticate", "Bearer realm=\""+zone.Config.Authorization.GetRealm()+"\"")
+						writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusUnauthorized, "Download Token Required")
+					}
 				case http.MethodDelete:
-					err := zone.Backend.Purge(lookupPath)
+					fsMod, theETag := zone.getCurrentValidators(lookupPath, pListTable)
+					if !processMutationPreconditions(rw, req, true, fsMod, theETag) {
+						break
+					}
+					err := zone.purgeObject(lookupPath)
 					pAttr := zone.checkPathAttributes(lookupPath)
 					if zone.Config.CacheResponse.RequestLimitedCacheCheck && pAttr != nil {
 						pAttr.NotExpunged = false
@@ -143,15 +303,18 @@ func (zone *Zone) ZoneHandleRequest(rw http.ResponseWriter, req *http.Request) {
 				if zone.Config.CacheResponse.RequestLimitedCacheCheck && pAttr != nil {
 					pAttr.NotExpunged = false
 				}
-				zone.mutAccess.Lock()
-				if zone.AccessLimits[lookupPath] != nil {
-					zone.AccessLimits[lookupPath] = nil
-				}
-				zone.mutAccess.Unlock()
+				zone.clearAccessLimits(lookupPath)
 				utils.SetNeverCacheHeader(rw.Header())
-				writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotFound, "Object Not Found")
+				if req.Method != http.MethodDelete {
+					writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotFound, "Object Not Found")
+				} else if processMutationPreconditions(rw, req, false, time.Time{}, "") {
+					_ = zone.purgeObject(lookupPath)
+					writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNotFound, "Object Not Found")
+				}
 			}
 		} else {
+			zone.setRateLimitHeaders(rw.Header(), reqLimit)
+			utils.SetRetryAfterHeader(rw.Header(), zone.getRequestRetryAfter(reqLimit))
 			pAttr := zone.checkPathAttributes(lookupPath)
 			if zone.Config.CacheResponse.RequestLimitedCacheCheck && pAttr != nil && pAttr.NotExpunged {
 				pAttr.UpdateHeader(rw.Header())
@@ -161,16 +324,16 @@ func (zone *Zone) ZoneHandleRequest(rw http.ResponseWriter, req *http.Request) {
 				writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusTooManyRequests, "Too Many Requests")
 			}
 		}
-		if connLimit.LimitConf.YamlValid() {
-			connLimit.StopConnection()
-		}
+		zone.stopConnection(connLimit, connStarted)
 	} else {
+		zone.setRateLimitHeaders(rw.Header(), reqLimit)
 		utils.SetNeverCacheHeader(rw.Header())
+		utils.SetRetryAfterHeader(rw.Header(), zone.getConnectionRetryAfter(connLimit))
 		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusTooManyRequests, "Too Many Connections")
 	}
 }
 
-func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request, zLAccessLimts *limits.AccessLimit, lookupPath string, plistable bool, bwlim conf.BandwidthLimitYaml) {
+func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request, clientIP string, zLAccessLimts *limits.AccessLimit, lookupPath string, plistable bool, bwlim conf.BandwidthLimitYaml, bwBucket *limits.BandwidthBucket, objectPolicy conf.ObjectPolicyYaml) {
 	if zLAccessLimts.Gone {
 		utils.SetNeverCacheHeader(rw.Header())
 		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusGone, "Object Gone")
@@ -182,7 +345,7 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 			if zLAccessLimts.Expired() {
 				utils.SetNeverCacheHeader(rw.Header())
 				if zone.Config.AccessLimit.PurgeExpired {
-					err := zone.Backend.Purge(lookupPath)
+					err := zone.purgeObject(lookupPath)
 					if err == nil {
 						writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusGone, "Object Expired")
 					} else {
@@ -217,17 +380,13 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 							if zone.Config.DownloadResponse.OutputDisposition {
 								utils.SetDownloadHeaders(rw.Header(), zone.Config.DownloadResponse, utils.GetFilenameFromPath(lookupPath), rw.Header().Get("Content-Type"))
 							}
+							setPolicyHeaders(rw.Header(), objectPolicy)
 							if processSupportedPreconditionsForNext(rw, req, fsMod, theETag, zone.Config.CacheResponse.NotModifiedResponseUsingLastModified, zone.Config.CacheResponse.NotModifiedResponseUsingETags) {
 								httpRangeParts := processRangePreconditions(fsSize, rw, req, fsMod, theETag, zone.Config.AllowRange)
 								if httpRangeParts != nil {
 									if len(httpRangeParts) <= 1 {
 										utils.LogPrintln(4, "Send Start")
-										var theWriter io.Writer
-										if bwlim.YamlValid() {
-											theWriter = limits.GetLimitedBandwidthWriter(bwlim, rw)
-										} else {
-											theWriter = rw
-										}
+										theWriter := zone.getBandwidthWriter(rw, bwlim, bwBucket)
 										if len(httpRangeParts) == 1 {
 											theWriter = limits.NewPartialRangeWriter(theWriter, httpRangeParts[0])
 										}
@@ -257,12 +416,7 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 												theListingString += "\r\n"
 											}
 										}
-										var theWriter io.Writer
-										if bwlim.YamlValid() {
-											theWriter = limits.GetLimitedBandwidthWriter(bwlim, rw)
-										} else {
-											theWriter = rw
-										}
+										theWriter := zone.getBandwidthWriter(rw, bwlim, bwBucket)
 										multWriter := multipart.NewWriter(theWriter)
 										rw.Header().Set("Content-Type", "multipart/byteranges; boundary="+multWriter.Boundary())
 										utils.LogPrintln(3, "Content-Type: multipart/byteranges; boundary="+multWriter.Boundary())
@@ -321,42 +475,92 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 								rw.Header().Set("Cache-Control", "private")
 							}
 						}
+						setPolicyHeaders(rw.Header(), objectPolicy)
 						if fsSize >= 0 {
 							rw.Header().Set("Content-Length", strconv.FormatInt(fsSize, 10))
 							if fsSize > 0 {
 								theMimeType := zone.Backend.MimeType(lookupPath)
+								if objectPolicy.ContentType != "" {
+									theMimeType = objectPolicy.ContentType
+								}
+								if objectPolicy.Filename != "" {
+									theDownloadSettings := zone.Config.DownloadResponse
+									theDownloadSettings.OutputFilename = true
+									utils.SetDownloadHeaders(rw.Header(), theDownloadSettings, objectPolicy.Filename, theMimeType)
+								}
 								if theMimeType != "" {
-									if zone.Config.DownloadResponse.OutputDisposition {
+									if zone.Config.DownloadResponse.OutputDisposition && objectPolicy.Filename == "" {
 										utils.SetDownloadHeaders(rw.Header(), zone.Config.DownloadResponse, utils.GetFilenameFromPath(lookupPath), theMimeType)
 									}
 									rw.Header().Set("Content-Type", theMimeType)
 								}
+								objectPath := lookupPath
+								theEncoding := ""
+								if pEncoding, pPath := zone.negotiatePrecompressed(rw.Header(), req, lookupPath); pEncoding != "" {
+									if pSize, pMod, err := zone.Backend.Stats(pPath); err == nil && pSize >= 0 {
+										objectPath = pPath
+										fsSize = pSize
+										fsMod = pMod
+										theETag = zone.Backend.ETag(pPath)
+										if theETag == "" {
+											theETag = utils.GetValueForETagUsingAttributes(fsMod, fsSize)
+										}
+										rw.Header().Set("ETag", theETag)
+										utils.SetLastModifiedHeader(rw.Header(), fsMod)
+										rw.Header().Set("Content-Length", strconv.FormatInt(fsSize, 10))
+										rw.Header().Set("Content-Encoding", pEncoding)
+									}
+								}
+								var theEncoder io.WriteCloser
+								if objectPath == lookupPath {
+									varyHeader := rw.Header().Values("Vary")
+									theEncoding = zone.negotiateEncoding(rw.Header(), req, theMimeType, fsSize)
+									if theEncoding != "" {
+										var eErr error
+										theEncoder, eErr = newEncodingWriter(theEncoding, zone.getBandwidthWriter(rw, bwlim, bwBucket))
+										if eErr != nil {
+											utils.LogPrintln(1, "Encoding Error: "+eErr.Error())
+											theEncoding = ""
+											rw.Header().Del("Vary")
+											for _, v := range varyHeader {
+												rw.Header().Add("Vary", v)
+											}
+										}
+									}
+								}
+								if theEncoding != "" {
+									theETag = utils.GetETagForEncoding(theETag, theEncoding)
+									rw.Header().Set("ETag", theETag)
+									rw.Header().Set("Content-Encoding", theEncoding)
+									rw.Header().Del("Content-Length")
+								}
 								if processSupportedPreconditionsForNext(rw, req, fsMod, theETag, zone.Config.CacheResponse.NotModifiedResponseUsingLastModified, zone.Config.CacheResponse.NotModifiedResponseUsingETags) {
-									httpRangeParts := processRangePreconditions(fsSize, rw, req, fsMod, theETag, zone.Config.AllowRange)
+									httpRangeParts := processRangePreconditions(fsSize, rw, req, fsMod, theETag, zone.Config.AllowRange && theEncoding == "")
 									if httpRangeParts != nil {
-										if len(httpRangeParts) == 0 {
+										if len(httpRangeParts) == 0 && theEncoding != "" {
 											utils.LogPrintln(4, "Send Start")
-											var theWriter io.Writer
-											if bwlim.YamlValid() {
-												theWriter = limits.GetLimitedBandwidthWriter(bwlim, rw)
-											} else {
-												theWriter = rw
+											err = zone.writeObject(objectPath, fsSize, fsMod, theEncoder)
+											if cErr := theEncoder.Close(); err == nil {
+												err = cErr
 											}
-											err = zone.Backend.WriteData(lookupPath, theWriter)
 											if err != nil {
 												utils.LogPrintln(1, "Internal Error: "+err.Error())
 											} else {
 												utils.LogPrintln(4, "Send Complete")
 											}
-										} else if len(httpRangeParts) == 1 {
+										} else if len(httpRangeParts) == 0 {
 											utils.LogPrintln(4, "Send Start")
-											var theWriter io.Writer
-											if bwlim.YamlValid() {
-												theWriter = limits.GetLimitedBandwidthWriter(bwlim, rw)
+											theWriter := zone.getBandwidthWriter(rw, bwlim, bwBucket)
+											err = zone.writeObject(objectPath, fsSize, fsMod, theWriter)
+											if err != nil {
+												utils.LogPrintln(1, "Internal Error: "+err.Error())
 											} else {
-												theWriter = rw
+												utils.LogPrintln(4, "Send Complete")
 											}
-											err = zone.Backend.WriteDataRange(lookupPath, theWriter, httpRangeParts[0].Start, httpRangeParts[0].Length)
+										} else if len(httpRangeParts) == 1 {
+											utils.LogPrintln(4, "Send Start")
+											theWriter := zone.getBandwidthWriter(rw, bwlim, bwBucket)
+											err = zone.writeObjectRange(objectPath, fsSize, fsMod, theWriter, httpRangeParts[0].Start, httpRangeParts[0].Length)
 											if err != nil {
 												utils.LogPrintln(1, "Internal Error: "+err.Error())
 											} else {
@@ -364,12 +568,7 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 											}
 										} else {
 											utils.LogPrintln(4, "Send Start")
-											var theWriter io.Writer
-											if bwlim.YamlValid() {
-												theWriter = limits.GetLimitedBandwidthWriter(bwlim, rw)
-											} else {
-												theWriter = rw
-											}
+											theWriter := zone.getBandwidthWriter(rw, bwlim, bwBucket)
 											mWriter := multipart.NewWriter(theWriter)
 											rw.Header().Set("Content-Type", "multipart/byteranges; boundary="+mWriter.Boundary())
 											utils.LogPrintln(3, "Content-Type: multipart/byteranges; boundary="+mWriter.Boundary())
@@ -385,7 +584,7 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 													utils.LogPrintln(1, "Internal Error: "+err.Error())
 													break
 												}
-												err = zone.Backend.WriteDataRange(lookupPath, mimePart, currentPart.Start, currentPart.Length)
+												err = zone.writeObjectRange(objectPath, fsSize, fsMod, mimePart, currentPart.Start, currentPart.Length)
 												if err != nil {
 													utils.LogPrintln(1, "Internal Error: "+err.Error())
 													break
@@ -427,6 +626,278 @@ func (zone *Zone) handleZoneGetAndHead(rw http.ResponseWriter, req *http.Request
 	}
 }
 
+func (zone *Zone) handleZonePut(rw http.ResponseWriter, req *http.Request, clientIP string, lookupPath string) {
+	utils.SetNeverCacheHeader(rw.Header())
+	if !zone.Config.UploadSettings.YamlValid() || !zone.Config.UploadSettings.AddressContained(clientIP) {
+		writeResponseHeaderCanWriteBody(1, req.Method, rw, http.StatusForbidden, "Forbidden Method")
+		return
+	}
+	wBackend, ok := zone.Backend.(WritableBackend)
+	if !ok {
+		writeResponseHeaderCanWriteBody(1, req.Method, rw, http.StatusForbidden, "Zone Backend Not Writable")
+		return
+	}
+	pExists, pListTable := wBackend.Exists(lookupPath)
+	if pListTable || lookupPath == "." {
+		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusConflict, "Object Is A Directory")
+		return
+	}
+	fsMod, theETag := time.Time{}, ""
+	if pExists {
+		fsMod, theETag = zone.getCurrentValidators(lookupPath, false)
+	}
+	if !processMutationPreconditions(rw, req, pExists, fsMod, theETag) {
+		return
+	}
+	if pExists && !zone.Config.UploadSettings.AllowOverwrite {
+		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusConflict, "Object Already Exists")
+		return
+	}
+	var theBody io.Reader = req.Body
+	var sizeReader *utils.MaxSizeReader
+	if zone.Config.UploadSettings.MaxSize > 0 {
+		if req.ContentLength > zone.Config.UploadSettings.MaxSize {
+			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusRequestEntityTooLarge, "Object Too Large")
+			return
+		}
+		sizeReader = utils.NewMaxSizeReader(req.Body, zone.Config.UploadSettings.MaxSize)
+		theBody = sizeReader
+	}
+	utils.LogPrintln(4, "Receive Start")
+	err := wBackend.Store(lookupPath, theBody, req.ContentLength, req.Header.Get("Content-Type"))
+	if err != nil {
+		var storeErr *snowutils.StoreError
+		if sizeReader != nil && sizeReader.Exceeded {
+			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusRequestEntityTooLarge, "Object Too Large")
+		} else if errors.As(err, &storeErr) && storeErr.Conflict {
+			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusConflict, "Store Conflict: "+storeErr.Reason)
+		} else if errors.As(err, &storeErr) {
+			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusForbidden, "Store Forbidden: "+storeErr.Reason)
+		} else {
+			writeResponseHeaderCanWriteBody(1, req.Method, rw, http.StatusInternalServerError, "Store Error: "+err.Error())
+		}
+		return
+	}
+	utils.LogPrintln(4, "Receive Complete")
+	zone.invalidateObjectCache(lookupPath)
+
+	pAttr := zone.checkPathAttributes(lookupPath)
+	if zone.Config.CacheResponse.RequestLimitedCacheCheck && pAttr != nil {
+		pAttr.NotExpunged = false
+	}
+	zone.clearAccessLimits(lookupPath)
+	if pExists {
+		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusNoContent, "")
+	} else {
+		writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusCreated, "")
+	}
+}
+
+func (zone *Zone) getCurrentValidators(lookupPath string, plistable bool) (time.Time, string) {
+	fsSize, fsMod, err := zone.Backend.Stats(lookupPath)
+	if err != nil {
+		return time.Time{}, ""
+	}
+	if plistable {
+		list, err := zone.Backend.List(lookupPath)
+		if err != nil {
+			return fsMod, ""
+		}
+		fsSize = int64(utils.LengthOfStringSlice(list))
+	}
+	theETag := zone.Backend.ETag(lookupPath)
+	if theETag == "" {
+		theETag = utils.GetValueForETagUsingAttributes(fsMod, fsSize)
+	}
+	return fsMod, theETag
+}
+
+func (zone *Zone) startConnection(connLimit *limits.ConnectionLimit) bool {
+	if connLimit.LimitConf.YamlValid() && !connLimit.StartConnection() {
+		return false
+	}
+	if !zone.GlobalLimits.StartConnection() {
+		if connLimit.LimitConf.YamlValid() {
+			connLimit.StopConnection()
+		}
+		return false
+	}
+	if !zone.CDNLimits.StartConnection() {
+		zone.GlobalLimits.StopConnection()
+		if connLimit.LimitConf.YamlValid() {
+			connLimit.StopConnection()
+		}
+		return false
+	}
+	return true
+}
+
+func (zone *Zone) stopConnection(connLimit *limits.ConnectionLimit, started time.Time) {
+	zone.CDNLimits.FinishConnection(started)
+	zone.GlobalLimits.FinishConnection(started)
+	if connLimit.LimitConf.YamlValid() {
+		connLimit.FinishConnection(started)
+	}
+}
+
+func (zone *Zone) getConnectionRetryAfter(connLimit *limits.ConnectionLimit) time.Duration {
+	fallback := zone.Config.RateLimitHeaders.GetConnectionRetryAfter()
+	var toReturn time.Duration
+	for _, cl := range []*limits.ConnectionLimit{connLimit, zone.GlobalLimits.Connection, zone.CDNLimits.Connection} {
+		if !cl.LimitConf.YamlValid() {
+			continue
+		}
+		if retryAfter := cl.RetryAfter(fallback); retryAfter > toReturn {
+			toReturn = retryAfter
+		}
+	}
+	if toReturn == 0 {
+		return fallback
+	}
+	return toReturn
+}
+
+func (zone *Zone) startRequest(reqLimit *limits.RequestLimit) bool {
+	return limits.StartRequests(reqLimit, zone.GlobalLimits.Request, zone.CDNLimits.Request)
+}
+
+func (zone *Zone) getActiveRequestLimits(reqLimit *limits.RequestLimit) []*limits.RequestLimit {
+	var toReturn []*limits.RequestLimit
+	for _, rl := range []*limits.RequestLimit{reqLimit, zone.GlobalLimits.Request, zone.CDNLimits.Request} {
+		if rl.LimitConf.YamlValid() {
+			toReturn = append(toReturn, rl)
+		}
+	}
+	return toReturn
+}
+
+func (zone *Zone) setRateLimitHeaders(header http.Header, reqLimit *limits.RequestLimit) {
+	if !zone.Config.RateLimitHeaders.Enabled {
+		return
+	}
+	found := false
+	var cLimit, cRemaining uint
+	var cReset time.Duration
+	for _, rl := range zone.getActiveRequestLimits(reqLimit) {
+		limit, remaining, reset := rl.Status()
+		if !found || remaining < cRemaining || (remaining == cRemaining && reset > cReset) {
+			found = true
+			cLimit, cRemaining, cReset = limit, remaining, reset
+		}
+	}
+	if found {
+		utils.SetRateLimitHeaders(header, cLimit, cRemaining, cReset)
+	}
+}
+
+func (zone *Zone) getRequestRetryAfter(reqLimit *limits.RequestLimit) time.Duration {
+	var toReturn time.Duration
+	for _, rl := range zone.getActiveRequestLimits(reqLimit) {
+		if retryAfter := rl.RetryAfter(); retryAfter > toReturn {
+			toReturn = retryAfter
+		}
+	}
+	return toReturn
+}
+
+func (zone *Zone) getBandwidthBucket(clientIP string, bwlim conf.BandwidthLimitYaml) *limits.BandwidthBucket {
+	if !bwlim.YamlValid() {
+		return nil
+	}
+	switch bwlim.GetScope() {
+	case "response":
+		return nil
+	case "cdn":
+		return zone.CDNBandwidthBuckets.GetBucket(clientIP, bwlim)
+	default:
+		return zone.BandwidthBuckets.GetBucket(clientIP, bwlim)
+	}
+}
+
+func (zone *Zone) getBandwidthWriter(rw io.Writer, bwlim conf.BandwidthLimitYaml, bwBucket *limits.BandwidthBucket) io.Writer {
+	theWriter := zone.CDNLimits.GetBandwidthWriter(zone.GlobalLimits.GetBandwidthWriter(rw))
+	if bwBucket != nil {
+		theWriter = limits.NewBucketBandwidthWriter(bwBucket, theWriter)
+	} else if bwlim.YamlValid() && bwlim.GetScope() == "response" {
+		theWriter = limits.GetLimitedBandwidthWriter(bwlim, theWriter)
+	}
+	return theWriter
+}
+
+func (zone *Zone) getObjectCacheKey(objectPath string) objectcache.Key {
+	return objectcache.Key{Zone: zone.Config.Name, Path: objectPath}
+}
+
+func (zone *Zone) writeObject(objectPath string, size int64, modified time.Time, rw io.Writer) error {
+	if zone.ObjectCache == nil || !zone.ObjectCache.Cacheable(size) {
+		return zone.Backend.WriteData(objectPath, rw)
+	}
+	theKey := zone.getObjectCacheKey(objectPath)
+	if data, ok := zone.ObjectCache.Get(theKey, size, modified); ok {
+		_, err := rw.Write(data)
+		return err
+	}
+	fill := zone.ObjectCache.StartFill(theKey, size, modified, func(w io.Writer) error {
+		return zone.Backend.WriteData(objectPath, w)
+	})
+	written, ok, err := fill.StreamTo(rw)
+	if err != nil || ok {
+		return err
+	}
+	if written == 0 {
+		return zone.Backend.WriteData(objectPath, rw)
+	}
+	if written >= size {
+		return errors.New("object changed while reading")
+	}
+	return zone.Backend.WriteDataRange(objectPath, rw, written, size-written)
+}
+
+func (zone *Zone) writeObjectRange(objectPath string, size int64, modified time.Time, rw io.Writer, index int64, length int64) error {
+	if zone.ObjectCache != nil && zone.ObjectCache.Cacheable(size) {
+		if data, ok := zone.ObjectCache.Get(zone.getObjectCacheKey(objectPath), size, modified); ok {
+			if index < 0 || length < 0 || index+length > int64(len(data)) {
+				return errors.New("range out of bounds")
+			}
+			_, err := rw.Write(data[index : index+length])
+			return err
+		}
+	}
+	return zone.Backend.WriteDataRange(objectPath, rw, index, length)
+}
+
+func (zone *Zone) invalidateObjectCache(lookupPath string) {
+	if zone.ObjectCache != nil {
+		zone.ObjectCache.Invalidate(zone.getObjectCacheKey(lookupPath))
+		for _, e := range utils.SupportedEncodings {
+			zone.ObjectCache.Invalidate(zone.getObjectCacheKey(lookupPath + utils.GetEncodingExtension(e)))
+		}
+	}
+}
+
+func (zone *Zone) purgeObject(lookupPath string) error {
+	zone.invalidateObjectCache(lookupPath)
+	return zone.Backend.Purge(lookupPath)
+}
+
+func (zone *Zone) cleanLimitState(idleTimeout time.Duration, maxEntries int) {
+	zone.mutAccess.Lock()
+	limits.CleanStateMapFunc(zone.AccessLimits, idleTimeout, maxEntries, zone.removeAccessLimitPath)
+	zone.mutAccess.Unlock()
+	zone.mutRequest.Lock()
+	limits.CleanStateMap(zone.RequestLimits, idleTimeout, maxEntries)
+	zone.mutRequest.Unlock()
+	zone.mutConn.Lock()
+	limits.CleanStateMap(zone.ConnectionLimits, idleTimeout, maxEntries)
+	zone.mutConn.Unlock()
+	if zone.PathAttributes != nil {
+		zone.mutPathAttr.Lock()
+		limits.CleanStateMap(zone.PathAttributes, idleTimeout, maxEntries)
+		zone.mutPathAttr.Unlock()
+	}
+	zone.BandwidthBuckets.Clean(idleTimeout, maxEntries)
+}
+
 func (zone *Zone) ZoneHostAllowed(host string) bool {
 	if len(zone.Config.Domains) == 0 {
 		return true
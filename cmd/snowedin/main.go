@@ -78,6 +78,12 @@ func main() {
 		log.Fatalln("Failed to parse config.yml:", err)
 	}
 
+	//Signed URL generation:
+
+	if len(os.Args) > 1 && os.Args[1] == "sign" {
+		os.Exit(signCommand(configYml, os.Args[2:]))
+	}
+
 	//Server definitions:
 
 	log.Println("[Main] Starting up CDN server...")
@@ -122,6 +128,9 @@ func main() {
 			}
 		}
 
+		log.Printf("[Main] Saving CDN state...\n")
+		cdnServer.Close()
+
 		log.Printf("[Main] Signalling program exit...\n")
 		b := time.Now().Sub(a)
 		log.Printf("[Main] Took '%s' to fully shutdown modules\n", b.String())
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strings"
+	"time"
+)
+
+func signCommand(configYml conf.ConfigYaml, args []string) int {
+	if len(args) < 3 || len(args) > 4 {
+		_, _ = fmt.Fprintln(os.Stderr, "Usage: snowedin sign <zone> <path> <duration> [clientIP]")
+		return 2
+	}
+	validFor, err := time.ParseDuration(args[2])
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Invalid duration:", err)
+		return 2
+	}
+	clientIP := ""
+	if len(args) == 4 {
+		clientIP = args[3]
+	}
+	for _, z := range configYml.Zones {
+		if !strings.EqualFold(z.Name, args[0]) {
+			continue
+		}
+		if !z.SignedUrls.YamlValid() {
+			_, _ = fmt.Fprintln(os.Stderr, "Zone does not have signed URLs enabled:", args[0])
+			return 1
+		}
+		if z.SignedUrls.BindClientIP && clientIP == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "Zone requires a client IP to be bound to signed URLs:", args[0])
+			return 2
+		} else if !z.SignedUrls.BindClientIP {
+			clientIP = ""
+		}
+		lookupPath := strings.TrimPrefix(path.Clean("/"+args[1]), "/")
+		theQuery := utils.SignUrlQuery(z.SignedUrls.Secret, lookupPath, time.Now().Add(validFor), clientIP)
+		if z.Name == "" {
+			fmt.Println("/" + lookupPath + "?" + theQuery)
+		} else {
+			fmt.Println("/" + z.Name + "/" + lookupPath + "?" + theQuery)
+		}
+		return 0
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "Zone not found:", args[0])
+	return 1
+}
@@ -3,7 +3,28 @@ package conf
 import "time"
 
 type AccessLimitYaml struct {
-	PurgeExpired bool          `yaml:"purgeExpired"`
-	ExpireTime   time.Duration `yaml:"expireTime"`
-	AccessLimit  uint          `yaml:"accessLimit"`
+	PurgeExpired       bool          `yaml:"purgeExpired"`
+	ExpireTime         time.Duration `yaml:"expireTime"`
+	AccessLimit        uint          `yaml:"accessLimit"`
+	Scope              string        `yaml:"scope"`
+	DownloadTokens     []string      `yaml:"downloadTokens"`
+	StateFile          string        `yaml:"stateFile"`
+	StateFlushInterval time.Duration `yaml:"stateFlushInterval"`
+}
+
+func (aly AccessLimitYaml) GetStateFlushInterval() time.Duration {
+	if aly.StateFlushInterval.Seconds() < 1 {
+		return 1 * time.Minute
+	} else {
+		return aly.StateFlushInterval
+	}
+}
+
+func (aly AccessLimitYaml) GetScope() string {
+	switch aly.Scope {
+	case "clientIP", "token":
+		return aly.Scope
+	default:
+		return "object"
+	}
 }
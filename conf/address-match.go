@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"net/netip"
+	"strings"
+)
+
+func AddressMatches(entries []string, address string) bool {
+	addr, addrErr := netip.ParseAddr(address)
+	if addrErr == nil {
+		addr = addr.Unmap()
+	}
+	for _, s := range entries {
+		if strings.EqualFold(s, address) {
+			return true
+		}
+		if addrErr != nil {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			if prefix, err := netip.ParsePrefix(s); err == nil && prefix.Contains(addr) {
+				return true
+			}
+		} else if entryAddr, err := netip.ParseAddr(s); err == nil && entryAddr.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func ExpandAddressGroups(entries []string, groups map[string][]string) []string {
+	toReturn := expandAddressGroups(entries, groups, make(map[string]bool))
+	if len(toReturn) == 0 && len(entries) != 0 {
+		//Keep the unresolved group names so the entry does not become a match for other addresses
+		return entries
+	}
+	return toReturn
+}
+
+func expandAddressGroups(entries []string, groups map[string][]string, visited map[string]bool) []string {
+	var toReturn []string
+	for _, s := range entries {
+		if strings.HasPrefix(s, "@") {
+			groupName := s[1:]
+			if !visited[groupName] {
+				visited[groupName] = true
+				toReturn = append(toReturn, expandAddressGroups(groups[groupName], groups, visited)...)
+				visited[groupName] = false
+			}
+		} else {
+			toReturn = append(toReturn, s)
+		}
+	}
+	return toReturn
+}
@@ -0,0 +1,27 @@
+package conf
+
+type AuthorizationYaml struct {
+	BearerTokens    []string          `yaml:"bearerTokens"`
+	BasicUsers      map[string]string `yaml:"basicUsers"`
+	RemoteAddresses []string          `yaml:"remoteAddresses"`
+	Realm           string            `yaml:"realm"`
+}
+
+func (ay AuthorizationYaml) YamlValid() bool {
+	return len(ay.BearerTokens) != 0 || len(ay.BasicUsers) != 0 || len(ay.RemoteAddresses) != 0
+}
+
+func (ay AuthorizationYaml) CredentialsRequired() bool {
+	return len(ay.BearerTokens) != 0 || len(ay.BasicUsers) != 0
+}
+
+func (ay AuthorizationYaml) AddressContained(address string) bool {
+	return len(ay.RemoteAddresses) == 0 || AddressMatches(ay.RemoteAddresses, address)
+}
+
+func (ay AuthorizationYaml) GetRealm() string {
+	if ay.Realm == "" {
+		return "snowedin"
+	}
+	return ay.Realm
+}
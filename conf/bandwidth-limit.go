@@ -1,6 +1,7 @@
 package conf
 
 import (
+	"strconv"
 	"strings"
 	"time"
 )
@@ -8,6 +9,8 @@ import (
 type BandwidthLimitYaml struct {
 	Bytes           uint          `yaml:"bytes"`
 	Interval        time.Duration `yaml:"interval"`
+	Burst           uint          `yaml:"burst"`
+	Scope           string        `yaml:"scope"`
 	RemoteAddresses []string      `yaml:"remoteAddresses"`
 }
 
@@ -16,10 +19,27 @@ func (bly BandwidthLimitYaml) YamlValid() bool {
 }
 
 func (bly BandwidthLimitYaml) AddressContained(address string) bool {
-	for _, s := range bly.RemoteAddresses {
-		if strings.EqualFold(s, address) {
-			return true
-		}
+	return AddressMatches(bly.RemoteAddresses, address)
+}
+
+func (bly BandwidthLimitYaml) GetBurst() uint {
+	if bly.Burst == 0 {
+		return bly.Bytes
 	}
-	return false
+	return bly.Burst
+}
+
+func (bly BandwidthLimitYaml) GetScope() string {
+	switch strings.ToLower(bly.Scope) {
+	case "response":
+		return "response"
+	case "cdn":
+		return "cdn"
+	default:
+		return "zone"
+	}
+}
+
+func (bly BandwidthLimitYaml) GetBucketKey(address string) string {
+	return address + "/" + strconv.FormatUint(uint64(bly.Bytes), 10) + "/" + bly.Interval.String() + "/" + strconv.FormatUint(uint64(bly.GetBurst()), 10)
 }
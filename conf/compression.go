@@ -0,0 +1,50 @@
+package conf
+
+import "strings"
+
+var defaultCompressionEncodings = []string{"br", "zstd", "gzip"}
+
+var defaultCompressibleMimeTypes = []string{"text/*", "application/javascript", "application/json", "application/xml", "application/wasm", "image/svg+xml"}
+
+type CompressionYaml struct {
+	Enabled   bool     `yaml:"enabled"`
+	Encodings []string `yaml:"encodings"`
+	MimeTypes []string `yaml:"mimeTypes"`
+	MinSize   int64    `yaml:"minSize"`
+}
+
+func (cy CompressionYaml) GetEncodings() []string {
+	if len(cy.Encodings) == 0 {
+		return defaultCompressionEncodings
+	} else {
+		return cy.Encodings
+	}
+}
+
+func (cy CompressionYaml) GetMimeTypes() []string {
+	if len(cy.MimeTypes) == 0 {
+		return defaultCompressibleMimeTypes
+	} else {
+		return cy.MimeTypes
+	}
+}
+
+func (cy CompressionYaml) MimeTypeCompressible(mimeType string) bool {
+	if idx := strings.IndexByte(mimeType, ';'); idx > -1 {
+		mimeType = mimeType[:idx]
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "" {
+		return false
+	}
+	for _, m := range cy.GetMimeTypes() {
+		if strings.HasSuffix(m, "/*") {
+			if strings.HasPrefix(mimeType, strings.TrimSuffix(m, "*")) {
+				return true
+			}
+		} else if strings.EqualFold(m, mimeType) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,30 @@
 package conf
 
 type ConfigYaml struct {
-	LogLevel uint       `yaml:"logLevel"`
-	Listen   ListenYaml `yaml:"listen"`
-	Zones    []ZoneYaml `yaml:"zones"`
+	LogLevel      uint                `yaml:"logLevel"`
+	Listen        ListenYaml          `yaml:"listen"`
+	AddressGroups map[string][]string `yaml:"addressGroups"`
+	GlobalLimits  GlobalLimitsYaml    `yaml:"globalLimits"`
+	LimitState    LimitStateYaml      `yaml:"limitState"`
+	ObjectCache   ObjectCacheYaml     `yaml:"objectCache"`
+	Zones         []ZoneYaml          `yaml:"zones"`
+}
+
+func (cy *ConfigYaml) ExpandAddressGroups() {
+	cy.Listen.TrustedProxies = ExpandAddressGroups(cy.Listen.TrustedProxies, cy.AddressGroups)
+	cy.Listen.ProxyProtocol = ExpandAddressGroups(cy.Listen.ProxyProtocol, cy.AddressGroups)
+	for i := range cy.Zones {
+		zy := &cy.Zones[i]
+		for j := range zy.Limits.ConnectionLimits {
+			zy.Limits.ConnectionLimits[j].RemoteAddresses = ExpandAddressGroups(zy.Limits.ConnectionLimits[j].RemoteAddresses, cy.AddressGroups)
+		}
+		for j := range zy.Limits.RequestLimits {
+			zy.Limits.RequestLimits[j].RemoteAddresses = ExpandAddressGroups(zy.Limits.RequestLimits[j].RemoteAddresses, cy.AddressGroups)
+		}
+		for j := range zy.Limits.BandwidthLimits {
+			zy.Limits.BandwidthLimits[j].RemoteAddresses = ExpandAddressGroups(zy.Limits.BandwidthLimits[j].RemoteAddresses, cy.AddressGroups)
+		}
+		zy.UploadSettings.RemoteAddresses = ExpandAddressGroups(zy.UploadSettings.RemoteAddresses, cy.AddressGroups)
+		zy.Authorization.RemoteAddresses = ExpandAddressGroups(zy.Authorization.RemoteAddresses, cy.AddressGroups)
+	}
 }
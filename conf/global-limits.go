@@ -0,0 +1,23 @@
+package conf
+
+import "time"
+
+type GlobalLimitsYaml struct {
+	MaxConnections      uint          `yaml:"maxConnections"`
+	MaxRequests         uint          `yaml:"maxRequests"`
+	RequestRateInterval time.Duration `yaml:"requestRateInterval"`
+	BandwidthBytes      uint          `yaml:"bandwidthBytes"`
+	BandwidthInterval   time.Duration `yaml:"bandwidthInterval"`
+}
+
+func (gly GlobalLimitsYaml) GetLimitConnectionYaml() LimitConnectionYaml {
+	return LimitConnectionYaml{MaxConnections: gly.MaxConnections}
+}
+
+func (gly GlobalLimitsYaml) GetLimitRequestsYaml() LimitRequestsYaml {
+	return LimitRequestsYaml{MaxRequests: gly.MaxRequests, RequestRateInterval: gly.RequestRateInterval}
+}
+
+func (gly GlobalLimitsYaml) GetBandwidthLimitYaml() BandwidthLimitYaml {
+	return BandwidthLimitYaml{Bytes: gly.BandwidthBytes, Interval: gly.BandwidthInterval}
+}
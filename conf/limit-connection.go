@@ -1,7 +1,5 @@
 package conf
 
-import "strings"
-
 type LimitConnectionYaml struct {
 	MaxConnections  uint     `yaml:"maxConnections"`
 	RemoteAddresses []string `yaml:"remoteAddresses"`
@@ -12,10 +10,5 @@ func (lcy LimitConnectionYaml) YamlValid() bool {
 }
 
 func (lcy LimitConnectionYaml) AddressContained(address string) bool {
-	for _, s := range lcy.RemoteAddresses {
-		if strings.EqualFold(s, address) {
-			return true
-		}
-	}
-	return false
+	return AddressMatches(lcy.RemoteAddresses, address)
 }
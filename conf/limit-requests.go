@@ -1,25 +1,56 @@
 package conf
 
-import (
-	"strings"
-	"time"
+import "time"
+
+const (
+	RequestLimitModeFixedWindow   = "fixedWindow"
+	RequestLimitModeSlidingWindow = "slidingWindow"
+	RequestLimitModeTokenBucket   = "tokenBucket"
 )
 
 type LimitRequestsYaml struct {
+	Mode                string        `yaml:"mode"`
 	MaxRequests         uint          `yaml:"maxRequests"`
 	RequestRateInterval time.Duration `yaml:"requestRateInterval"`
+	RefillRate          float64       `yaml:"refillRate"`
+	Burst               uint          `yaml:"burst"`
 	RemoteAddresses     []string      `yaml:"remoteAddresses"`
 }
 
 func (lry LimitRequestsYaml) YamlValid() bool {
+	if lry.GetMode() == RequestLimitModeTokenBucket {
+		return lry.GetBurst() != 0 && lry.GetRefillRate() > 0
+	}
 	return lry.MaxRequests != 0 && lry.RequestRateInterval.Milliseconds() >= 10
 }
 
 func (lry LimitRequestsYaml) AddressContained(address string) bool {
-	for _, s := range lry.RemoteAddresses {
-		if strings.EqualFold(s, address) {
-			return true
-		}
+	return AddressMatches(lry.RemoteAddresses, address)
+}
+
+func (lry LimitRequestsYaml) GetMode() string {
+	switch lry.Mode {
+	case RequestLimitModeSlidingWindow, RequestLimitModeTokenBucket:
+		return lry.Mode
+	default:
+		return RequestLimitModeFixedWindow
+	}
+}
+
+func (lry LimitRequestsYaml) GetRefillRate() float64 {
+	if lry.RefillRate > 0 {
+		return lry.RefillRate
+	} else if lry.RequestRateInterval.Milliseconds() >= 10 {
+		return float64(lry.MaxRequests) / lry.RequestRateInterval.Seconds()
+	} else {
+		return 0
+	}
+}
+
+func (lry LimitRequestsYaml) GetBurst() uint {
+	if lry.Burst == 0 {
+		return lry.MaxRequests
+	} else {
+		return lry.Burst
 	}
-	return false
 }
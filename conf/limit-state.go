@@ -0,0 +1,25 @@
+package conf
+
+import "time"
+
+type LimitStateYaml struct {
+	CleanInterval time.Duration `yaml:"cleanInterval"`
+	IdleTimeout   time.Duration `yaml:"idleTimeout"`
+	MaxEntries    int           `yaml:"maxEntries"`
+}
+
+func (lsy LimitStateYaml) GetCleanInterval() time.Duration {
+	if lsy.CleanInterval.Seconds() < 1 {
+		return 1 * time.Minute
+	} else {
+		return lsy.CleanInterval
+	}
+}
+
+func (lsy LimitStateYaml) GetIdleTimeout() time.Duration {
+	if lsy.IdleTimeout.Seconds() < 1 {
+		return 10 * time.Minute
+	} else {
+		return lsy.IdleTimeout
+	}
+}
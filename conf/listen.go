@@ -3,12 +3,15 @@ package conf
 import "time"
 
 type ListenYaml struct {
-	Web          string        `yaml:"web"`
-	Api          string        `yaml:"api"`
-	ReadTimeout  time.Duration `yaml:"readTimeout"`
-	WriteTimeout time.Duration `yaml:"writeTimeout"`
-	IdleTimeout  time.Duration `yaml:"idleTimeout"`
-	Identify     bool          `yaml:"identify"`
+	Web            string        `yaml:"web"`
+	Api            string        `yaml:"api"`
+	ReadTimeout    time.Duration `yaml:"readTimeout"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	Identify       bool          `yaml:"identify"`
+	TrustedProxies []string      `yaml:"trustedProxies"`
+	ClientIPHeader string        `yaml:"clientIPHeader"`
+	ProxyProtocol  []string      `yaml:"proxyProtocol"`
 }
 
 func (ly ListenYaml) GetReadTimeout() time.Duration {
@@ -0,0 +1,22 @@
+package conf
+
+type ObjectCacheYaml struct {
+	MaxSize       int64 `yaml:"maxSize"`
+	MaxObjectSize int64 `yaml:"maxObjectSize"`
+}
+
+func (ocy ObjectCacheYaml) YamlValid() bool {
+	return ocy.MaxSize > 0
+}
+
+func (ocy ObjectCacheYaml) GetMaxObjectSize() int64 {
+	maxObjectSize := ocy.MaxObjectSize
+	if maxObjectSize <= 0 {
+		maxObjectSize = 1048576
+	}
+	if maxObjectSize > ocy.MaxSize {
+		return ocy.MaxSize
+	} else {
+		return maxObjectSize
+	}
+}
@@ -0,0 +1,51 @@
+package conf
+
+import "time"
+
+type ObjectPolicyYaml struct {
+	ExpireTime   *time.Duration    `yaml:"expireTime,omitempty"`
+	AccessLimit  *uint             `yaml:"accessLimit,omitempty"`
+	CacheControl string            `yaml:"cacheControl,omitempty"`
+	ContentType  string            `yaml:"contentType,omitempty"`
+	Filename     string            `yaml:"filename,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+}
+
+func (opy ObjectPolicyYaml) Merge(override ObjectPolicyYaml) ObjectPolicyYaml {
+	if override.ExpireTime != nil {
+		opy.ExpireTime = override.ExpireTime
+	}
+	if override.AccessLimit != nil {
+		opy.AccessLimit = override.AccessLimit
+	}
+	if override.CacheControl != "" {
+		opy.CacheControl = override.CacheControl
+	}
+	if override.ContentType != "" {
+		opy.ContentType = override.ContentType
+	}
+	if override.Filename != "" {
+		opy.Filename = override.Filename
+	}
+	if len(override.Headers) != 0 {
+		theHeaders := make(map[string]string, len(opy.Headers)+len(override.Headers))
+		for k, v := range opy.Headers {
+			theHeaders[k] = v
+		}
+		for k, v := range override.Headers {
+			theHeaders[k] = v
+		}
+		opy.Headers = theHeaders
+	}
+	return opy
+}
+
+func (opy ObjectPolicyYaml) ApplyToAccessLimit(aly AccessLimitYaml) AccessLimitYaml {
+	if opy.ExpireTime != nil {
+		aly.ExpireTime = *opy.ExpireTime
+	}
+	if opy.AccessLimit != nil {
+		aly.AccessLimit = *opy.AccessLimit
+	}
+	return aly
+}
@@ -0,0 +1,16 @@
+package conf
+
+import "time"
+
+type RateLimitHeadersYaml struct {
+	Enabled              bool          `yaml:"enabled"`
+	ConnectionRetryAfter time.Duration `yaml:"connectionRetryAfter"`
+}
+
+func (rlhy RateLimitHeadersYaml) GetConnectionRetryAfter() time.Duration {
+	if rlhy.ConnectionRetryAfter.Seconds() < 1 {
+		return 1 * time.Second
+	} else {
+		return rlhy.ConnectionRetryAfter
+	}
+}
@@ -0,0 +1,10 @@
+package conf
+
+type SignedUrlsYaml struct {
+	Secret       string `yaml:"secret"`
+	BindClientIP bool   `yaml:"bindClientIP"`
+}
+
+func (suy SignedUrlsYaml) YamlValid() bool {
+	return suy.Secret != ""
+}
@@ -0,0 +1,15 @@
+package conf
+
+type UploadSettingsYaml struct {
+	RemoteAddresses []string `yaml:"remoteAddresses"`
+	MaxSize         int64    `yaml:"maxSize"`
+	AllowOverwrite  bool     `yaml:"allowOverwrite"`
+}
+
+func (usy UploadSettingsYaml) YamlValid() bool {
+	return len(usy.RemoteAddresses) != 0
+}
+
+func (usy UploadSettingsYaml) AddressContained(address string) bool {
+	return AddressMatches(usy.RemoteAddresses, address)
+}
@@ -6,8 +6,15 @@ type ZoneYaml struct {
 	AllowRange       bool                 `yaml:"allowRange"`
 	CacheResponse    CacheSettingsYaml    `yaml:"cacheResponse"`
 	DownloadResponse DownloadSettingsYaml `yaml:"downloadResponse"`
+	Compression      CompressionYaml      `yaml:"compression"`
+	UseObjectCache   bool                 `yaml:"useObjectCache"`
 	AccessLimit      AccessLimitYaml      `yaml:"accessLimit"`
+	UploadSettings   UploadSettingsYaml   `yaml:"uploadSettings"`
+	Authorization    AuthorizationYaml    `yaml:"authorization"`
+	SignedUrls       SignedUrlsYaml       `yaml:"signedUrls"`
 	Limits           LimitsYaml           `yaml:"limits"`
+	GlobalLimits     GlobalLimitsYaml     `yaml:"globalLimits"`
+	RateLimitHeaders RateLimitHeadersYaml `yaml:"rateLimitHeaders"`
 	Backend          string               `yaml:"backend"`
 	BackendSettings  map[string]string    `yaml:"backendSettings"`
 }
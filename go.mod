@@ -3,8 +3,10 @@ module snow.mrmelon54.xyz/snowedin
 go 1.18
 
 require (
+	github.com/andybalholm/brotli v1.0.6
 	github.com/gorilla/mux v1.8.0
 	github.com/joho/godotenv v1.4.0
-	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
+	github.com/klauspost/compress v1.17.0
+	golang.org/x/crypto v0.14.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+func NewMetadataCache[T any](ttl time.Duration, maxEntries int) *MetadataCache[T] {
+	return &MetadataCache[T]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+type MetadataCache[T any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type metadataCacheEntry[T any] struct {
+	key     string
+	value   T
+	fetched time.Time
+}
+
+func (mc *MetadataCache[T]) Get(key string) (value T, ok bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	elem, found := mc.entries[key]
+	if !found {
+		return value, false
+	}
+	entry := elem.Value.(*metadataCacheEntry[T])
+	if mc.ttl > 0 && time.Since(entry.fetched) > mc.ttl {
+		mc.lru.Remove(elem)
+		delete(mc.entries, key)
+		return value, false
+	}
+	mc.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (mc *MetadataCache[T]) Put(key string, value T) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if elem, found := mc.entries[key]; found {
+		entry := elem.Value.(*metadataCacheEntry[T])
+		entry.value = value
+		entry.fetched = time.Now()
+		mc.lru.MoveToFront(elem)
+		return
+	}
+	mc.entries[key] = mc.lru.PushFront(&metadataCacheEntry[T]{key: key, value: value, fetched: time.Now()})
+	for mc.maxEntries > 0 && mc.lru.Len() > mc.maxEntries {
+		oldest := mc.lru.Back()
+		mc.lru.Remove(oldest)
+		delete(mc.entries, oldest.Value.(*metadataCacheEntry[T]).key)
+	}
+}
+
+func (mc *MetadataCache[T]) Delete(key string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if elem, found := mc.entries[key]; found {
+		mc.lru.Remove(elem)
+		delete(mc.entries, key)
+	}
+}
+
+func (mc *MetadataCache[T]) Len() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.lru.Len()
+}
@@ -0,0 +1,18 @@
+package utils
+
+type StoreError struct {
+	Reason   string
+	Conflict bool
+}
+
+func (e *StoreError) Error() string {
+	return e.Reason
+}
+
+func NewStoreForbiddenError(reason string) *StoreError {
+	return &StoreError{Reason: reason}
+}
+
+func NewStoreConflictError(reason string) *StoreError {
+	return &StoreError{Reason: reason, Conflict: true}
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"snow.mrmelon54.xyz/snowedin/conf"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+func NewProxyProtocolListener(listener net.Listener, trustedUpstreams []string, headerTimeout time.Duration) net.Listener {
+	return &ProxyProtocolListener{
+		Listener:         listener,
+		trustedUpstreams: trustedUpstreams,
+		headerTimeout:    headerTimeout,
+	}
+}
+
+type ProxyProtocolListener struct {
+	net.Listener
+	trustedUpstreams []string
+	headerTimeout    time.Duration
+}
+
+func (l *ProxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	remoteIP := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+		remoteIP = host
+	}
+	if !conf.AddressMatches(l.trustedUpstreams, remoteIP) {
+		return conn, nil
+	}
+	return &ProxyProtocolConn{
+		Conn:          conn,
+		reader:        bufio.NewReader(conn),
+		headerTimeout: l.headerTimeout,
+		once:          &sync.Once{},
+	}, nil
+}
+
+type ProxyProtocolConn struct {
+	net.Conn
+	reader        *bufio.Reader
+	headerTimeout time.Duration
+	once          *sync.Once
+	remoteAddr    net.Addr
+	headerErr     error
+}
+
+func (c *ProxyProtocolConn) Read(p []byte) (n int, err error) {
+	c.once.Do(c.readHeader)
+	if c.headerErr != nil {
+		return 0, c.headerErr
+	}
+	return c.reader.Read(p)
+}
+
+func (c *ProxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *ProxyProtocolConn) readHeader() {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+	defer func() {
+		_ = c.Conn.SetReadDeadline(time.Time{})
+	}()
+	c.remoteAddr, c.headerErr = readProxyProtocolHeader(c.reader)
+	if c.headerErr != nil {
+		logPrintln(1, "PROXY Protocol Error: "+c.headerErr.Error()+" from "+c.Conn.RemoteAddr().String())
+		_ = c.Conn.Close()
+	}
+}
+
+func readProxyProtocolHeader(reader *bufio.Reader) (net.Addr, error) {
+	signature, err := reader.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(signature, proxyProtocolV2Signature) {
+		return readProxyProtocolV2Header(reader)
+	}
+	if bytes.HasPrefix(signature, []byte("PROXY ")) {
+		return readProxyProtocolV1Header(reader)
+	}
+	return nil, errors.New("missing proxy protocol header")
+}
+
+func readProxyProtocolV1Header(reader *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, 107)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= 107 {
+			return nil, errors.New("proxy protocol v1 header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("invalid proxy protocol v1 header")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.New("invalid proxy protocol v1 header")
+	}
+	srcIP, dstIP := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	isTCP6 := fields[1] == "TCP6"
+	if srcIP == nil || dstIP == nil || strings.Contains(fields[2], ":") != isTCP6 || strings.Contains(fields[3], ":") != isTCP6 {
+		return nil, errors.New("invalid proxy protocol v1 address")
+	}
+	srcPort, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, errors.New("invalid proxy protocol v1 port")
+	}
+	if _, err := strconv.ParseUint(fields[5], 10, 16); err != nil {
+		return nil, errors.New("invalid proxy protocol v1 port")
+	}
+	return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, nil
+}
+
+func readProxyProtocolV2Header(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, errors.New("unsupported proxy protocol v2 version")
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	switch header[12] & 0x0F {
+	case 0x00:
+		return nil, nil
+	case 0x01:
+	default:
+		return nil, errors.New("unsupported proxy protocol v2 command")
+	}
+	switch header[13] >> 4 {
+	case 0x01:
+		if len(payload) < 12 {
+			return nil, errors.New("invalid proxy protocol v2 address")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x02:
+		if len(payload) < 36 {
+			return nil, errors.New("invalid proxy protocol v2 address")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func proxyProtocolV2Header(command byte, family byte, payload []byte) []byte {
+	header := append([]byte{}, proxyProtocolV2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:16], uint16(len(payload)))
+	return append(header, payload...)
+}
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	ipv4Payload := []byte{203, 0, 113, 7, 192, 0, 2, 1, 0x1F, 0x90, 0x01, 0xBB}
+	ipv6Payload := make([]byte, 36)
+	copy(ipv6Payload[0:16], net.ParseIP("2001:db8::7"))
+	copy(ipv6Payload[16:32], net.ParseIP("2001:db8::1"))
+	binary.BigEndian.PutUint16(ipv6Payload[32:34], 8080)
+	binary.BigEndian.PutUint16(ipv6Payload[34:36], 443)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		address string
+		err     bool
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nGET"), "203.0.113.7:8080", false},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 8080 443\r\nGET"), "[2001:db8::7]:8080", false},
+		{"v1 tcp6 mapped", []byte("PROXY TCP6 ::ffff:203.0.113.7 ::ffff:192.0.2.1 8080 443\r\nGET"), "203.0.113.7:8080", false},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nGET"), "", false},
+		{"v1 unknown with addresses", []byte("PROXY UNKNOWN ffff:f...f:ffff ffff:f...f:ffff 65535 65535\r\nGET"), "", false},
+		{"v1 missing crlf", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\nGET"), "", true},
+		{"v1 truncated", []byte("PROXY TCP4 203.0.113.7"), "", true},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", true},
+		{"v1 missing field", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 8080\r\n"), "", true},
+		{"v1 extra field", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 8080 443 1\r\n"), "", true},
+		{"v1 lowercase protocol", []byte("PROXY tcp4 203.0.113.7 192.0.2.1 8080 443\r\n"), "", true},
+		{"v1 unsupported protocol", []byte("PROXY UDP4 203.0.113.7 192.0.2.1 8080 443\r\n"), "", true},
+		{"v1 invalid address", []byte("PROXY TCP4 203.0.113.300 192.0.2.1 8080 443\r\n"), "", true},
+		{"v1 hostname address", []byte("PROXY TCP4 localhost 192.0.2.1 8080 443\r\n"), "", true},
+		{"v1 tcp4 with ipv6 address", []byte("PROXY TCP4 2001:db8::7 192.0.2.1 8080 443\r\n"), "", true},
+		{"v1 tcp6 with ipv4 address", []byte("PROXY TCP6 203.0.113.7 2001:db8::1 8080 443\r\n"), "", true},
+		{"v1 invalid destination", []byte("PROXY TCP4 203.0.113.7 nope 8080 443\r\n"), "", true},
+		{"v1 port out of range", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 65536 443\r\n"), "", true},
+		{"v1 negative port", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 -1 443\r\n"), "", true},
+		{"v1 invalid destination port", []byte("PROXY TCP4 203.0.113.7 192.0.2.1 8080 https\r\n"), "", true},
+		{"v2 ipv4", proxyProtocolV2Header(0x01, 0x11, ipv4Payload), "203.0.113.7:8080", false},
+		{"v2 ipv6", proxyProtocolV2Header(0x01, 0x21, ipv6Payload), "[2001:db8::7]:8080", false},
+		{"v2 ipv4 with tlvs", proxyProtocolV2Header(0x01, 0x11, append(append([]byte{}, ipv4Payload...), 0x04, 0x00, 0x01, 0x00)), "203.0.113.7:8080", false},
+		{"v2 local", proxyProtocolV2Header(0x00, 0x00, nil), "", false},
+		{"v2 unspecified family", proxyProtocolV2Header(0x01, 0x00, nil), "", false},
+		{"v2 bad version", append(append(append([]byte{}, proxyProtocolV2Signature...), 0x11, 0x11, 0, 12), ipv4Payload...), "", true},
+		{"v2 bad command", proxyProtocolV2Header(0x02, 0x11, ipv4Payload), "", true},
+		{"v2 short ipv4 payload", proxyProtocolV2Header(0x01, 0x11, ipv4Payload[:8]), "", true},
+		{"v2 short ipv6 payload", proxyProtocolV2Header(0x01, 0x21, ipv6Payload[:20]), "", true},
+		{"v2 truncated payload", proxyProtocolV2Header(0x01, 0x11, ipv4Payload)[:20], "", true},
+		{"v2 truncated header", proxyProtocolV2Signature, "", true},
+		{"missing header", []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"), "", true},
+		{"empty", []byte{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := readProxyProtocolHeader(bufio.NewReader(bytes.NewReader(tt.input)))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got address %v", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.address == "" {
+				if addr != nil {
+					t.Fatalf("expected no address, got %v", addr)
+				}
+			} else if addr == nil || addr.String() != tt.address {
+				t.Fatalf("expected address %s, got %v", tt.address, addr)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	tests := []struct {
+		name      string
+		trusted   []string
+		send      string
+		remoteIP  string
+		body      string
+		readError bool
+	}{
+		{"trusted upstream", []string{"127.0.0.0/8"}, "PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nhello", "203.0.113.7", "hello", false},
+		{"trusted upstream without header", []string{"127.0.0.1"}, "hello world!", "127.0.0.1", "", true},
+		{"trusted upstream with malformed header", []string{"127.0.0.1"}, "PROXY TCP4 203.0.113.7\r\nhello", "127.0.0.1", "", true},
+		{"untrusted source", []string{"10.0.0.0/8"}, "PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nhello", "127.0.0.1", "PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nhello", false},
+		{"no trusted upstreams", nil, "PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nhello", "127.0.0.1", "PROXY TCP4 203.0.113.7 192.0.2.1 8080 443\r\nhello", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ppListener := NewProxyProtocolListener(listener, tt.trusted, time.Second)
+			defer ppListener.Close()
+
+			go func() {
+				client, err := net.Dial("tcp", listener.Addr().String())
+				if err != nil {
+					return
+				}
+				_, _ = client.Write([]byte(tt.send))
+				_ = client.(*net.TCPConn).CloseWrite()
+				_, _ = io.Copy(io.Discard, client)
+				_ = client.Close()
+			}()
+
+			conn, err := ppListener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			if host != tt.remoteIP {
+				t.Fatalf("expected remote address %s, got %s", tt.remoteIP, host)
+			}
+			body, err := io.ReadAll(conn)
+			if tt.readError {
+				if err == nil {
+					t.Fatalf("expected a read error, got %q", body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Fatalf("expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package web
 
 import (
-	"github.com/tomasen/realip"
 	"log"
 	"net/http"
+	"snow.mrmelon54.xyz/snowedin/cdn/utils"
 	"strconv"
 )
 
@@ -34,7 +34,7 @@ func logRequest(req *http.Request) {
 	} else {
 		logPrintln(2, req.Method+" "+req.RequestURI+" "+req.Proto)
 		logPrintln(2, "Host: "+req.Host)
-		logPrintln(2, "Client Address: "+realip.FromRequest(req))
+		logPrintln(2, "Client Address: "+utils.GetClientIP(req, ListenConfig.TrustedProxies, ListenConfig.ClientIPHeader))
 	}
 	logHeaders(req.Header)
 }
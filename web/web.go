@@ -3,12 +3,15 @@ package web
 import (
 	"github.com/gorilla/mux"
 	"log"
+	"net"
 	"net/http"
 	"snow.mrmelon54.xyz/snowedin/cdn"
+	"snow.mrmelon54.xyz/snowedin/conf"
 	"strings"
 )
 
 var LogLevel uint = 0
+var ListenConfig conf.ListenYaml
 
 func New(cdnIn *cdn.CDN) *http.Server {
 	router := mux.NewRouter()
@@ -34,12 +37,20 @@ func New(cdnIn *cdn.CDN) *http.Server {
 		IdleTimeout:  cdnIn.Config.Listen.GetIdleTimeout(),
 	}
 	LogLevel = cdnIn.Config.LogLevel
-	go runBackgroundHttp(s)
+	ListenConfig = cdnIn.Config.Listen
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		log.Fatalf("[Http] Error trying to listen for the http server: %s\n", err.Error())
+	}
+	if len(cdnIn.Config.Listen.ProxyProtocol) != 0 {
+		listener = NewProxyProtocolListener(listener, cdnIn.Config.Listen.ProxyProtocol, cdnIn.Config.Listen.GetReadTimeout())
+	}
+	go runBackgroundHttp(s, listener)
 	return s
 }
 
-func runBackgroundHttp(s *http.Server) {
-	err := s.ListenAndServe()
+func runBackgroundHttp(s *http.Server, listener net.Listener) {
+	err := s.Serve(listener)
 	if err != nil {
 		if err == http.ErrServerClosed {
 			logPrintln(0, "The http server shutdown successfully")
@@ -51,7 +62,7 @@ func runBackgroundHttp(s *http.Server) {
 
 func zoneHandlerFunc(rw http.ResponseWriter, req *http.Request, cdnIn *cdn.CDN) {
 	logRequest(req)
-	if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodDelete {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodDelete || req.Method == http.MethodPut {
 		vars := mux.Vars(req)
 		var otherZone *cdn.Zone
 		var targetZone *cdn.Zone
@@ -76,7 +87,7 @@ func zoneHandlerFunc(rw http.ResponseWriter, req *http.Request, cdnIn *cdn.CDN)
 		}
 		targetZone.ZoneHandleRequest(rw, req)
 	} else {
-		rw.Header().Set("Allow", http.MethodOptions+", "+http.MethodGet+", "+http.MethodHead+", "+http.MethodDelete)
+		rw.Header().Set("Allow", http.MethodOptions+", "+http.MethodGet+", "+http.MethodHead+", "+http.MethodDelete+", "+http.MethodPut)
 		if req.Method == http.MethodOptions {
 			writeResponseHeaderCanWriteBody(2, req.Method, rw, http.StatusOK, "")
 		} else {